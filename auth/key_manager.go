@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"dgw-technical-test/config"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/repository"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// reloadCooldown limits how often an unknown kid triggers a reload of the
+// keys, so garbage tokens cannot hammer the database.
+const reloadCooldown = 5 * time.Second
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	private   interface{}
+	public    interface{}
+	createdAt time.Time
+	retired   bool
+}
+
+// KeyManager signs access tokens with the current key of the configured
+// algorithm and verifies tokens signed by any key that has not expired yet.
+// Asymmetric keys are stored in the database, rotated on a schedule and
+// published as a JWKS. HS256 keeps using the shared JWT_SECRET and has no
+// rotation nor public keys.
+type KeyManager struct {
+	SigningKeyRepository repository.SigningKeyRepository
+	AuthConfig           *config.AuthConfig
+
+	mu         sync.RWMutex
+	keys       map[string]*signingKey
+	current    *signingKey
+	lastReload time.Time
+}
+
+func NewKeyManager(signingKeyRepository repository.SigningKeyRepository, authConfig *config.AuthConfig) (*KeyManager, error) {
+	manager := &KeyManager{
+		SigningKeyRepository: signingKeyRepository,
+		AuthConfig:           authConfig,
+	}
+
+	if authConfig.SigningAlgorithm == jwt.SigningMethodHS256.Alg() {
+		key := &signingKey{
+			id:      "hs256",
+			method:  jwt.SigningMethodHS256,
+			private: []byte(authConfig.JWTSecret),
+			public:  []byte(authConfig.JWTSecret),
+		}
+		manager.keys = map[string]*signingKey{key.id: key}
+		manager.current = key
+		return manager, nil
+	}
+
+	if _, err := manager.RotateIfDue(); err != nil {
+		return nil, err
+	}
+
+	if err := manager.Reload(); err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+// Algorithm returns the algorithm new tokens are signed with.
+func (manager *KeyManager) Algorithm() string {
+	return manager.AuthConfig.SigningAlgorithm
+}
+
+// Sign signs the claims with the current key and sets its kid header.
+func (manager *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	manager.mu.RLock()
+	key := manager.current
+	manager.mu.RUnlock()
+
+	if key == nil {
+		return "", ErrUnknownKey
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the verification key of a token from its kid header. It
+// is meant to be passed to jwt.Parse.
+func (manager *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key := manager.find(kid)
+	if key == nil && manager.AuthConfig.SigningAlgorithm != jwt.SigningMethodHS256.Alg() {
+		// Another instance may have rotated the key since the last reload.
+		if manager.reloadAllowed() {
+			if err := manager.Reload(); err != nil {
+				return nil, err
+			}
+			key = manager.find(kid)
+		}
+	}
+
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("token algorithm %s does not match key %s", token.Method.Alg(), key.id)
+	}
+
+	return key.public, nil
+}
+
+// ValidMethods lists the algorithms accepted when verifying tokens.
+func (manager *KeyManager) ValidMethods() []string {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	methods := []string{}
+	for _, key := range manager.keys {
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+
+	return methods
+}
+
+// RotateIfDue generates a new signing key when the current one is older than
+// the rotation interval. Retired keys keep verifying tokens for one access
+// token lifetime. It reports whether a new key was stored.
+func (manager *KeyManager) RotateIfDue() (bool, error) {
+	if manager.AuthConfig.SigningAlgorithm == jwt.SigningMethodHS256.Alg() {
+		return false, nil
+	}
+
+	now := time.Now()
+	staleBefore := now.Add(-manager.AuthConfig.KeyRotationInterval)
+	verifyUntil := now.Add(manager.AuthConfig.AccessTokenTTL + time.Minute)
+
+	// Generating a key is expensive, so skip it while the current key is
+	// fresh. RotateIfStale checks again under a lock.
+	createdAt, err := manager.SigningKeyRepository.CurrentCreatedAt(manager.AuthConfig.SigningAlgorithm)
+	if err != nil {
+		return false, err
+	}
+
+	if createdAt != nil && !createdAt.Before(staleBefore) {
+		return false, nil
+	}
+
+	key, err := generateSigningKey(manager.AuthConfig.SigningAlgorithm)
+	if err != nil {
+		return false, err
+	}
+
+	rotated, err := manager.SigningKeyRepository.RotateIfStale(key, staleBefore, verifyUntil)
+	if err != nil {
+		return false, err
+	}
+
+	if rotated {
+		if err := manager.Reload(); err != nil {
+			return true, err
+		}
+	}
+
+	return rotated, nil
+}
+
+// Reload reads the keys that are still valid from the database.
+func (manager *KeyManager) Reload() error {
+	if manager.AuthConfig.SigningAlgorithm == jwt.SigningMethodHS256.Alg() {
+		return nil
+	}
+
+	now := time.Now()
+	stored, err := manager.SigningKeyRepository.FindActive(now)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(stored))
+	var current *signingKey
+	for i := range stored {
+		key, err := parseSigningKey(&stored[i])
+		if err != nil {
+			return err
+		}
+
+		keys[key.id] = key
+		if !key.retired && key.method.Alg() == manager.AuthConfig.SigningAlgorithm && (current == nil || key.createdAt.After(current.createdAt)) {
+			current = key
+		}
+	}
+
+	if current == nil {
+		return fmt.Errorf("no signing key for %s", manager.AuthConfig.SigningAlgorithm)
+	}
+
+	manager.mu.Lock()
+	manager.keys = keys
+	manager.current = current
+	manager.lastReload = now
+	manager.mu.Unlock()
+
+	return nil
+}
+
+// JWKS returns the public keys that verify tokens as a JSON Web Key Set.
+func (manager *KeyManager) JWKS() JWKSet {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range manager.keys {
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	return set
+}
+
+func (manager *KeyManager) find(kid string) *signingKey {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	return manager.keys[kid]
+}
+
+func (manager *KeyManager) reloadAllowed() bool {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	return time.Since(manager.lastReload) >= reloadCooldown
+}
+
+func generateSigningKey(algorithm string) (*entity.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.SigningKey{
+		KID:        uuid.NewString(),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}, nil
+}
+
+func parseSigningKey(stored *entity.SigningKey) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(stored.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s: invalid PEM", stored.KID)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", stored.KID, err)
+	}
+
+	key := &signingKey{
+		id:        stored.KID,
+		private:   private,
+		createdAt: stored.CreatedAt,
+		retired:   stored.RetiredAt != nil,
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.method = jwt.SigningMethodRS256
+		key.public = &private.PublicKey
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.public = private.Public()
+	default:
+		return nil, fmt.Errorf("signing key %s: unsupported key type %T", stored.KID, private)
+	}
+
+	if key.method.Alg() != stored.Algorithm {
+		return nil, fmt.Errorf("signing key %s: stored as %s but is a %s key", stored.KID, stored.Algorithm, key.method.Alg())
+	}
+
+	return key, nil
+}
@@ -0,0 +1,410 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"dgw-technical-test/config"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcRequestTimeout = 10 * time.Second
+	// oidcMetadataRefresh is how long the discovery document is cached.
+	oidcMetadataRefresh = time.Hour
+)
+
+var (
+	ErrOIDCDisabled   = errors.New("single sign-on is not configured")
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCIdentity is what the identity provider vouches for in an ID token.
+type OIDCIdentity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Groups            []string
+	// MultiFactor is set when the provider reports a multi-factor login
+	// in the amr claim (RFC 8176).
+	MultiFactor bool
+}
+
+// OIDCProvider runs the authorization code flow with PKCE against an OpenID
+// Connect provider. Endpoints come from the discovery document of the issuer
+// and its signing keys are cached, reloading when an unknown kid shows up.
+type OIDCProvider struct {
+	OIDCConfig *config.OIDCConfig
+	Client     *http.Client
+
+	mu         sync.RWMutex
+	metadata   *oidcMetadata
+	loadedAt   time.Time
+	keys       map[string]interface{}
+	lastReload time.Time
+}
+
+func NewOIDCProvider(oidcConfig *config.OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{
+		OIDCConfig: oidcConfig,
+		Client:     &http.Client{Timeout: oidcRequestTimeout},
+	}
+}
+
+// PKCEChallenge derives the S256 code challenge of a code verifier
+// (RFC 7636).
+func PKCEChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns where to send the user to sign in at the provider.
+func (provider *OIDCProvider) AuthCodeURL(state string, nonce string, codeVerifier string) (string, error) {
+	metadata, err := provider.discover()
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.OIDCConfig.ClientID},
+		"redirect_uri":          {provider.OIDCConfig.RedirectURL},
+		"scope":                 {strings.Join(provider.OIDCConfig.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {PKCEChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the authorization code at the token endpoint and returns
+// the identity from the verified ID token.
+func (provider *OIDCProvider) Exchange(code string, codeVerifier string, nonce string) (*OIDCIdentity, error) {
+	metadata, err := provider.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {provider.OIDCConfig.RedirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {provider.OIDCConfig.ClientID},
+	}
+
+	request, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	if provider.OIDCConfig.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(provider.OIDCConfig.ClientID), url.QueryEscape(provider.OIDCConfig.ClientSecret))
+	}
+
+	response, err := provider.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	if tokenResponse.Error != "" {
+		return nil, fmt.Errorf("token endpoint: %s %s", tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+
+	if response.StatusCode != http.StatusOK || tokenResponse.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint answered %d without an ID token", response.StatusCode)
+	}
+
+	return provider.verifyIDToken(tokenResponse.IDToken, nonce)
+}
+
+func (provider *OIDCProvider) verifyIDToken(idToken string, nonce string) (*OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, provider.keyfunc,
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(provider.OIDCConfig.Issuer),
+		jwt.WithAudience(provider.OIDCConfig.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	identity := &OIDCIdentity{
+		Issuer:            provider.OIDCConfig.Issuer,
+		Subject:           subject,
+		Groups:            stringsClaim(claims[provider.OIDCConfig.GroupsClaim]),
+		MultiFactor:       slices.Contains(stringsClaim(claims["amr"]), "mfa"),
+		PreferredUsername: stringClaim(claims["preferred_username"]),
+		Email:             stringClaim(claims["email"]),
+	}
+
+	// Some providers send email_verified as a string.
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity, nil
+}
+
+func (provider *OIDCProvider) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	provider.mu.RLock()
+	key, ok := provider.lookupKey(kid)
+	canReload := time.Since(provider.lastReload) >= reloadCooldown
+	provider.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if !canReload {
+		return nil, ErrUnknownKey
+	}
+
+	if err := provider.reloadKeys(); err != nil {
+		return nil, err
+	}
+
+	provider.mu.RLock()
+	defer provider.mu.RUnlock()
+
+	if key, ok := provider.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// lookupKey finds the key by kid; tokens without a kid are accepted when the
+// provider publishes a single key. The caller holds mu.
+func (provider *OIDCProvider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(provider.keys) == 1 {
+		for _, key := range provider.keys {
+			return key, true
+		}
+	}
+
+	key, ok := provider.keys[kid]
+	return key, ok
+}
+
+func (provider *OIDCProvider) reloadKeys() error {
+	metadata, err := provider.discover()
+	if err != nil {
+		return err
+	}
+
+	var keySet JWKSet
+	if err := provider.getJSON(metadata.JWKSURI, &keySet); err != nil {
+		return err
+	}
+
+	keys := make(map[string]interface{}, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJWK(jwk)
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.KeyID] = key
+	}
+
+	provider.mu.Lock()
+	provider.keys = keys
+	provider.lastReload = time.Now()
+	provider.mu.Unlock()
+
+	return nil
+}
+
+func (provider *OIDCProvider) discover() (*oidcMetadata, error) {
+	if !provider.OIDCConfig.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	provider.mu.RLock()
+	metadata := provider.metadata
+	fresh := time.Since(provider.loadedAt) < oidcMetadataRefresh
+	provider.mu.RUnlock()
+
+	if metadata != nil && fresh {
+		return metadata, nil
+	}
+
+	metadata = new(oidcMetadata)
+	if err := provider.getJSON(provider.OIDCConfig.Issuer+"/.well-known/openid-configuration", metadata); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(metadata.Issuer, "/") != provider.OIDCConfig.Issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, expected %q", metadata.Issuer, provider.OIDCConfig.Issuer)
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("discovery document misses an endpoint")
+	}
+
+	provider.mu.Lock()
+	provider.metadata = metadata
+	provider.loadedAt = time.Now()
+	provider.mu.Unlock()
+
+	return metadata, nil
+}
+
+func (provider *OIDCProvider) getJSON(target string, value interface{}) error {
+	response, err := provider.Client.Get(target)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s answered %d", target, response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// parseJWK turns a public JSON Web Key into the key type jwt verifies with.
+func parseJWK(jwk JWK) (interface{}, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Curve)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Curve)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", jwk.KeyType)
+}
+
+func stringClaim(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// stringsClaim reads a claim that holds a list of strings or a single one.
+func stringsClaim(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"database/sql"
+	"dgw-technical-test/config"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/password"
+	"dgw-technical-test/repository"
+	"errors"
+	"log"
+	"os"
+	"time"
+)
+
+// bootstrapAdmin creates the first administrator from the BOOTSTRAP_ADMIN_*
+// environment variables. It does nothing once an active admin exists, so the
+// variables can be removed after the first start.
+func bootstrapAdmin(userRepository repository.UserRepository, passwordHasher password.Hasher) {
+	username := os.Getenv("BOOTSTRAP_ADMIN_USERNAME")
+	email := os.Getenv("BOOTSTRAP_ADMIN_EMAIL")
+	password := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+
+	if username == "" || email == "" || password == "" {
+		return
+	}
+
+	admins, err := userRepository.CountAdmins()
+	if err != nil {
+		log.Fatalf("failed to count admins: %v", err)
+	}
+
+	if admins > 0 {
+		return
+	}
+
+	hashPassword, err := passwordHasher.Hash(password)
+	if err != nil {
+		log.Fatalf("failed to hash bootstrap admin password: %v", err)
+	}
+
+	verifiedAt := time.Now()
+	user := &entity.User{
+		Username:        username,
+		Email:           email,
+		Password:        hashPassword,
+		Role:            entity.RoleAdmin,
+		EmailVerifiedAt: &verifiedAt,
+	}
+
+	if err := userRepository.Register(user); err != nil {
+		log.Fatalf("failed to create bootstrap admin: %v", err)
+	}
+
+	log.Printf("Created bootstrap admin %q\n", username)
+}
+
+// verifyOIDCRoles refuses to start when OIDC_DEFAULT_ROLE or OIDC_ROLE_MAPPING
+// names a role that does not exist, which would fail every login it applies
+// to.
+func verifyOIDCRoles(roleRepository repository.RoleRepository, oidcConfig *config.OIDCConfig) {
+	if !oidcConfig.Enabled() {
+		return
+	}
+
+	for _, role := range oidcConfig.Roles() {
+		if _, err := roleRepository.FindByName(role); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Fatalf("single sign-on assigns the unknown role %q, create it or fix OIDC_DEFAULT_ROLE and OIDC_ROLE_MAPPING", role)
+			}
+			log.Fatalf("failed to verify single sign-on roles: %v", err)
+		}
+	}
+}
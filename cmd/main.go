@@ -1,8 +1,14 @@
 package main
 
 import (
+	"dgw-technical-test/auth"
 	"dgw-technical-test/config"
 	"dgw-technical-test/handler"
+	"dgw-technical-test/job"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/migration"
+	"dgw-technical-test/notification"
+	"dgw-technical-test/password"
 	"dgw-technical-test/repository"
 	"dgw-technical-test/routes"
 	"log"
@@ -31,18 +37,93 @@ import (
 // @BasePath  /
 
 func main() {
+	db := config.NewDatabase()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(db, os.Args[2:])
+		db.Close()
+		return
+	}
+
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		log.Fatalf("failed to verify migrations: %v", err)
+	}
+
+	if pending > 0 {
+		log.Fatalf("%d pending migrations, run `migrate up` first", pending)
+	}
+
 	app := fiber.New()
 	app.Use(logger.New())
 
-	db := config.NewDatabase()
 	validate := validator.New()
+	roleRepository := repository.NewRoleRepository(db)
+	roleHandler := handler.NewRoleHandler(roleRepository, validate)
+
+	authConfig := config.NewAuthConfig()
+	signingKeyRepository := repository.NewSigningKeyRepository(db)
+	keyManager, err := auth.NewKeyManager(signingKeyRepository, authConfig)
+	if err != nil {
+		log.Fatalf("failed to load signing keys: %v", err)
+	}
+	jwksHandler := handler.NewJWKSHandler(keyManager)
+
+	refreshTokenRepository := repository.NewRefreshTokenRepository(db)
+	sessionRepository := repository.NewSessionRepository(db)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(db)
+	loginPolicy := config.NewLoginPolicy()
+	passwordChecker := password.NewChecker(config.NewPasswordPolicy())
+	passwordHasher := password.NewHasher(config.NewPasswordHashConfig())
 	userRepository := repository.NewUserRepository(db)
-	userHandler := handler.NewUserHandler(userRepository, validate)
+	sessionHandler := handler.NewSessionHandler(sessionRepository)
+
+	mailConfig := config.NewMailConfig()
+	mailSender := mailer.NewMailer(mailConfig)
+	emailVerificationRepository := repository.NewEmailVerificationRepository(db)
+	twoFactorRepository := repository.NewTwoFactorRepository(db)
+	twoFactorPolicy := config.NewTwoFactorPolicy()
+	userHandler, err := handler.NewUserHandler(userRepository, roleRepository, refreshTokenRepository, sessionRepository, loginAttemptRepository, emailVerificationRepository, twoFactorRepository, authConfig, loginPolicy, twoFactorPolicy, keyManager, passwordChecker, passwordHasher, mailSender, validate)
+	if err != nil {
+		log.Fatalf("failed to create user handler: %v", err)
+	}
+	oidcConfig := config.NewOIDCConfig()
+	verifyOIDCRoles(roleRepository, oidcConfig)
+	oidcHandler := handler.NewOIDCHandler(userHandler, auth.NewOIDCProvider(oidcConfig), repository.NewOIDCRepository(db), oidcConfig)
+	emailVerificationHandler := handler.NewEmailVerificationHandler(userRepository, emailVerificationRepository, authConfig, mailSender, validate)
+	twoFactorHandler := handler.NewTwoFactorHandler(userRepository, twoFactorRepository, sessionRepository, twoFactorPolicy, passwordHasher, validate)
+	passwordResetRepository := repository.NewPasswordResetRepository(db)
+	apiKeyRepository := repository.NewApiKeyRepository(db)
+	apiKeyHandler := handler.NewApiKeyHandler(apiKeyRepository, config.NewApiKeyPolicy(), validate)
+	adminHandler := handler.NewAdminHandler(userRepository, roleRepository, loginAttemptRepository, passwordResetRepository, authConfig, passwordChecker, passwordHasher, mailSender, validate)
+	passwordResetHandler := handler.NewPasswordResetHandler(userRepository, passwordResetRepository, loginAttemptRepository, authConfig, passwordChecker, passwordHasher, mailSender, validate)
+
+	bootstrapAdmin(userRepository, passwordHasher)
 
 	bookRepository := repository.NewBookRepository(db)
 	bookHandler := handler.NewBookHandler(bookRepository, validate)
 
-	routes.NewRoute(app, *userHandler, *bookHandler)
+	notifier := notification.NewNotifier(mailConfig, mailSender, userRepository, bookRepository)
+	reservationRepository := repository.NewReservationRepository(db)
+	reservationPolicy := config.NewReservationPolicy()
+	reservationHandler := handler.NewReservationHandler(reservationRepository, bookRepository, reservationPolicy, notifier, validate)
+
+	rentRepository := repository.NewRentRepository(db)
+	rentPolicy := config.NewRentPolicy()
+	rentHandler := handler.NewRentHandler(rentRepository, bookRepository, reservationRepository, rentPolicy, reservationPolicy, notifier, validate)
+
+	privacyHandler := handler.NewPrivacyHandler(userHandler, rentRepository, reservationRepository, apiKeyRepository, repository.NewErasureRepository(db))
+
+	routes.NewRoute(app, keyManager, sessionRepository, userRepository, roleRepository, apiKeyRepository, twoFactorPolicy, *userHandler, *bookHandler, *rentHandler, *reservationHandler, *roleHandler, *adminHandler, *sessionHandler, *passwordResetHandler, *emailVerificationHandler, *twoFactorHandler, *apiKeyHandler, *oidcHandler, *privacyHandler, *jwksHandler)
+
+	stopJobs := make(chan struct{})
+	go job.RunReservationExpiry(reservationRepository, reservationPolicy, notifier, stopJobs)
+	go job.RunKeyRotation(keyManager, authConfig.KeyCheckInterval, stopJobs)
 
 	errChan := make(chan error, 1)
 	stopChan := make(chan os.Signal, 1)
@@ -57,6 +138,9 @@ func main() {
 	}()
 
 	defer func() {
+		log.Println("Stopping background jobs...")
+		close(stopJobs)
+
 		log.Println("Closing database connection...")
 		db.Close()
 
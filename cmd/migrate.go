@@ -0,0 +1,74 @@
+package main
+
+import (
+	"dgw-technical-test/migration"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const migrateUsage = "usage: migrate up | down | status | to <version>"
+
+// runMigrate handles the migrate subcommand, e.g. `go run ./cmd migrate up`.
+func runMigrate(db *sqlx.DB, args []string) {
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "to":
+		if len(args) < 2 {
+			log.Fatal(migrateUsage)
+		}
+
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Fatalf("invalid version %q", args[1])
+		}
+
+		err = migrator.To(version)
+	case "status":
+		err = printMigrationStatus(migrator)
+	default:
+		log.Fatal(migrateUsage)
+	}
+
+	if err != nil {
+		log.Fatalf("migrate %s failed: %v", args[0], err)
+	}
+
+	if args[0] != "status" {
+		if err := printMigrationStatus(migrator); err != nil {
+			log.Fatalf("failed to read migration status: %v", err)
+		}
+	}
+}
+
+func printMigrationStatus(migrator *migration.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+
+		fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return nil
+}
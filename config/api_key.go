@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+type ApiKeyPolicy struct {
+	DefaultLifetime time.Duration
+	MaxLifetime     time.Duration
+	MaxPerUser      int
+}
+
+func NewApiKeyPolicy() *ApiKeyPolicy {
+	return &ApiKeyPolicy{
+		DefaultLifetime: getEnvDuration("API_KEY_DEFAULT_LIFETIME", 90*24*time.Hour),
+		MaxLifetime:     getEnvDuration("API_KEY_MAX_LIFETIME", 365*24*time.Hour),
+		MaxPerUser:      getEnvInt("API_KEY_MAX_PER_USER", 10),
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+type AuthConfig struct {
+	SigningAlgorithm    string
+	JWTSecret           string
+	KeyRotationInterval time.Duration
+	KeyCheckInterval    time.Duration
+	AccessTokenTTL      time.Duration
+	RefreshTokenTTL     time.Duration
+	PasswordResetTTL    time.Duration
+	PasswordResetURL    string
+
+	EmailVerificationTTL            time.Duration
+	EmailVerificationURL            string
+	EmailVerificationResendInterval time.Duration
+
+	// LocalLoginEnabled turns off password login and registration when
+	// users must sign in through single sign-on.
+	LocalLoginEnabled bool
+	// ReauthenticationMaxAge is how recent the login of a single sign-on
+	// user must be to stand in for their password.
+	ReauthenticationMaxAge time.Duration
+}
+
+func NewAuthConfig() *AuthConfig {
+	authConfig := &AuthConfig{
+		SigningAlgorithm:    getEnvString("JWT_SIGNING_ALGORITHM", "RS256"),
+		JWTSecret:           os.Getenv("JWT_SECRET"),
+		KeyRotationInterval: getEnvDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		KeyCheckInterval:    getEnvDuration("JWT_KEY_CHECK_INTERVAL", time.Minute),
+		AccessTokenTTL:      getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:     getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PasswordResetTTL:    getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetURL:    getEnvString("PASSWORD_RESET_URL", "http://localhost:8080/reset-password"),
+
+		EmailVerificationTTL:            getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		EmailVerificationURL:            getEnvString("EMAIL_VERIFICATION_URL", "http://localhost:8080/verify-email"),
+		EmailVerificationResendInterval: getEnvDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+
+		LocalLoginEnabled:      getEnvBool("LOCAL_LOGIN_ENABLED", true),
+		ReauthenticationMaxAge: getEnvDuration("REAUTHENTICATION_MAX_AGE", 10*time.Minute),
+	}
+
+	switch authConfig.SigningAlgorithm {
+	case "RS256", "EdDSA":
+	case "HS256":
+		if authConfig.JWTSecret == "" {
+			log.Fatal("JWT_SECRET is required when JWT_SIGNING_ALGORITHM is HS256")
+		}
+	default:
+		log.Fatalf("invalid value for JWT_SIGNING_ALGORITHM: %s", authConfig.SigningAlgorithm)
+	}
+
+	return authConfig
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("invalid value for %s: %v", key, err)
+	}
+
+	return result
+}
+
+// getEnvIntBetween reads an integer that must lie between min and max,
+// inclusive.
+func getEnvIntBetween(key string, fallback int, min int, max int) int {
+	result := getEnvInt(key, fallback)
+	if result < min || result > max {
+		log.Fatalf("invalid value for %s: %d is not between %d and %d", key, result, min, max)
+	}
+
+	return result
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("invalid value for %s: %v", key, err)
+	}
+
+	return result
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid value for %s: %v", key, err)
+	}
+
+	return result
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid value for %s: %v", key, err)
+	}
+
+	return result
+}
+
+func getEnvString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
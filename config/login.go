@@ -0,0 +1,24 @@
+package config
+
+import "time"
+
+// LoginPolicy throttles failed logins. Each account and each client IP gets
+// locked out once its failures within FailureWindow reach the threshold; the
+// lockout doubles with every further failure up to MaxLockout.
+type LoginPolicy struct {
+	AccountMaxFailures int
+	IPMaxFailures      int
+	FailureWindow      time.Duration
+	BaseLockout        time.Duration
+	MaxLockout         time.Duration
+}
+
+func NewLoginPolicy() *LoginPolicy {
+	return &LoginPolicy{
+		AccountMaxFailures: getEnvInt("LOGIN_ACCOUNT_MAX_FAILURES", 5),
+		IPMaxFailures:      getEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		FailureWindow:      getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		BaseLockout:        getEnvDuration("LOGIN_BASE_LOCKOUT", time.Minute),
+		MaxLockout:         getEnvDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+	}
+}
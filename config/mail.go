@@ -0,0 +1,40 @@
+package config
+
+import (
+	"log"
+	"os"
+)
+
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+}
+
+func NewMailConfig() *MailConfig {
+	mailConfig := &MailConfig{
+		Driver:       getEnvString("MAIL_DRIVER", "log"),
+		From:         getEnvString("MAIL_FROM", "no-reply@localhost"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		FileDir:      getEnvString("MAIL_FILE_DIR", "mail"),
+	}
+
+	switch mailConfig.Driver {
+	case "log", "file":
+	case "smtp":
+		if mailConfig.SMTPHost == "" {
+			log.Fatal("SMTP_HOST is required when MAIL_DRIVER is smtp")
+		}
+	default:
+		log.Fatalf("invalid value for MAIL_DRIVER: %s", mailConfig.Driver)
+	}
+
+	return mailConfig
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// OIDCRoleMapping gives members of an identity provider group a role.
+type OIDCRoleMapping struct {
+	Group string
+	Role  string
+}
+
+// OIDCConfig configures single sign-on against an OpenID Connect provider.
+// It is disabled while OIDC_ISSUER is empty.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	RoleMappings []OIDCRoleMapping
+	DefaultRole  string
+	StateTTL     time.Duration
+}
+
+func NewOIDCConfig() *OIDCConfig {
+	oidcConfig := &OIDCConfig{
+		Issuer:       strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  getEnvString("OIDC_REDIRECT_URL", "http://localhost:8080/users/oidc/callback"),
+		Scopes:       strings.Fields(getEnvString("OIDC_SCOPES", "openid profile email")),
+		GroupsClaim:  getEnvString("OIDC_GROUPS_CLAIM", "groups"),
+		DefaultRole:  getEnvString("OIDC_DEFAULT_ROLE", "Customer"),
+		StateTTL:     getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+	}
+
+	// OIDC_ROLE_MAPPING lists group=role pairs; the first group the user is
+	// a member of decides the role.
+	for _, pair := range strings.Split(os.Getenv("OIDC_ROLE_MAPPING"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(group) == "" || strings.TrimSpace(role) == "" {
+			log.Fatalf("invalid value for OIDC_ROLE_MAPPING: %s", pair)
+		}
+
+		oidcConfig.RoleMappings = append(oidcConfig.RoleMappings, OIDCRoleMapping{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+	}
+
+	if oidcConfig.Enabled() && oidcConfig.ClientID == "" {
+		log.Fatal("OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+	}
+
+	return oidcConfig
+}
+
+func (oidcConfig *OIDCConfig) Enabled() bool {
+	return oidcConfig.Issuer != ""
+}
+
+// Roles lists every role single sign-on can assign.
+func (oidcConfig *OIDCConfig) Roles() []string {
+	roles := []string{oidcConfig.DefaultRole}
+	for _, mapping := range oidcConfig.RoleMappings {
+		roles = append(roles, mapping.Role)
+	}
+
+	return roles
+}
+
+// RoleFor returns the role of a user who is a member of the groups.
+func (oidcConfig *OIDCConfig) RoleFor(groups []string) string {
+	for _, mapping := range oidcConfig.RoleMappings {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role
+			}
+		}
+	}
+
+	return oidcConfig.DefaultRole
+}
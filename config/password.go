@@ -0,0 +1,60 @@
+package config
+
+import (
+	"log"
+	"math"
+	"os"
+)
+
+// PasswordPolicy describes the passwords users may choose. MaxLength is in
+// bytes because bcrypt rejects passwords longer than 72 bytes.
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	BreachedDir      string
+}
+
+func NewPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 10),
+		MaxLength:        getEnvInt("PASSWORD_MAX_LENGTH", 72),
+		RequireLowercase: getEnvBool("PASSWORD_REQUIRE_LOWERCASE", true),
+		RequireUppercase: getEnvBool("PASSWORD_REQUIRE_UPPERCASE", true),
+		RequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		BreachedDir:      os.Getenv("BREACHED_PASSWORDS_DIR"),
+	}
+}
+
+// PasswordHashConfig chooses how new password hashes are made. Hashes made
+// with another algorithm or weaker parameters are upgraded at login. The
+// bcrypt cost must lie between 4 and 31, and Argon2Memory is in KiB.
+type PasswordHashConfig struct {
+	Algorithm         string
+	BcryptCost        int
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+}
+
+func NewPasswordHashConfig() *PasswordHashConfig {
+	hashConfig := &PasswordHashConfig{
+		Algorithm:         getEnvString("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		BcryptCost:        getEnvIntBetween("PASSWORD_BCRYPT_COST", 12, 4, 31),
+		Argon2Memory:      getEnvIntBetween("PASSWORD_ARGON2_MEMORY", 64*1024, 8, math.MaxInt32),
+		Argon2Iterations:  getEnvIntBetween("PASSWORD_ARGON2_ITERATIONS", 3, 1, math.MaxInt32),
+		Argon2Parallelism: getEnvIntBetween("PASSWORD_ARGON2_PARALLELISM", 2, 1, math.MaxUint8),
+	}
+
+	switch hashConfig.Algorithm {
+	case "argon2id", "bcrypt":
+	default:
+		log.Fatalf("invalid value for PASSWORD_HASH_ALGORITHM: %s", hashConfig.Algorithm)
+	}
+
+	return hashConfig
+}
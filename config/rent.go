@@ -0,0 +1,21 @@
+package config
+
+type RentPolicy struct {
+	DurationDays     int
+	RenewalDays      int
+	MaxRenewals      int
+	LateFeePerDay    float64
+	LateFeeGraceDays int
+	LateFeeCap       float64
+}
+
+func NewRentPolicy() *RentPolicy {
+	return &RentPolicy{
+		DurationDays:     getEnvInt("RENT_DURATION_DAYS", 7),
+		RenewalDays:      getEnvInt("RENT_RENEWAL_DAYS", 7),
+		MaxRenewals:      getEnvInt("RENT_MAX_RENEWALS", 2),
+		LateFeePerDay:    getEnvFloat("LATE_FEE_PER_DAY", 0),
+		LateFeeGraceDays: getEnvInt("LATE_FEE_GRACE_DAYS", 0),
+		LateFeeCap:       getEnvFloat("LATE_FEE_CAP", 0),
+	}
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+type ReservationPolicy struct {
+	HoldDuration   time.Duration
+	ExpiryInterval time.Duration
+}
+
+func NewReservationPolicy() *ReservationPolicy {
+	return &ReservationPolicy{
+		HoldDuration:   getEnvDuration("RESERVATION_HOLD_DURATION", 48*time.Hour),
+		ExpiryInterval: getEnvDuration("RESERVATION_EXPIRY_INTERVAL", time.Minute),
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type TwoFactorPolicy struct {
+	Issuer               string
+	RequiredRoles        []string
+	ChallengeTTL         time.Duration
+	MaxChallengeAttempts int
+	RecoveryCodeCount    int
+}
+
+func NewTwoFactorPolicy() *TwoFactorPolicy {
+	var requiredRoles []string
+	for _, role := range strings.Split(os.Getenv("TWO_FACTOR_REQUIRED_ROLES"), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			requiredRoles = append(requiredRoles, role)
+		}
+	}
+
+	return &TwoFactorPolicy{
+		Issuer:               getEnvString("TOTP_ISSUER", "DGW-Technical-Test"),
+		RequiredRoles:        requiredRoles,
+		ChallengeTTL:         getEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		MaxChallengeAttempts: getEnvInt("TWO_FACTOR_MAX_ATTEMPTS", 5),
+		RecoveryCodeCount:    getEnvInt("TWO_FACTOR_RECOVERY_CODES", 10),
+	}
+}
+
+// RequiredFor reports whether users of the role must use two-factor
+// authentication.
+func (policy *TwoFactorPolicy) RequiredFor(role string) bool {
+	return slices.Contains(policy.RequiredRoles, role)
+}
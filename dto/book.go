@@ -1,10 +1,12 @@
 package dto
 
+import "dgw-technical-test/entity"
+
 type BookCreateRequest struct {
 	Name          string  `json:"name" validate:"required"`
 	Genre         string  `json:"genre" validate:"required"`
 	Author        string  `json:"author" validate:"required"`
-	PublishedDate string  `json:"published_date" validate:"required"`
+	PublishedDate string  `json:"published_date" validate:"required,datetime=2006-01-02"`
 	Stock         int     `json:"stock" validate:"required"`
 	Price         float64 `json:"price" validate:"required"`
 }
@@ -23,7 +25,41 @@ type BookUpdateRequest struct {
 	Name          string  `json:"name" validate:"required"`
 	Genre         string  `json:"genre" validate:"required"`
 	Author        string  `json:"author" validate:"required"`
-	PublishedDate string  `json:"published_date" validate:"required"`
+	PublishedDate string  `json:"published_date" validate:"required,datetime=2006-01-02"`
 	Stock         int     `json:"stock" validate:"required"`
 	Price         float64 `json:"price" validate:"required"`
 }
+
+type BookListQuery struct {
+	Page     int      `query:"page" validate:"omitempty,min=1"`
+	Limit    int      `query:"limit" validate:"omitempty,min=1,max=100"`
+	Genre    string   `query:"genre"`
+	Author   string   `query:"author"`
+	MinPrice *float64 `query:"min_price" validate:"omitempty,min=0"`
+	MaxPrice *float64 `query:"max_price" validate:"omitempty,min=0"`
+	InStock  bool     `query:"in_stock"`
+	Sort     string   `query:"sort" validate:"omitempty,oneof=name price published_date"`
+	Order    string   `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+type BookListResponse struct {
+	Data       []entity.Book `json:"data"`
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+	Total      int           `json:"total"`
+	TotalPages int           `json:"total_pages"`
+}
+
+type BookSearchQuery struct {
+	Query string `query:"q" validate:"required"`
+	Page  int    `query:"page" validate:"omitempty,min=1"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type BookSearchResponse struct {
+	Data       []entity.BookSearchResult `json:"data"`
+	Page       int                       `json:"page"`
+	Limit      int                       `json:"limit"`
+	Total      int                       `json:"total"`
+	TotalPages int                       `json:"total_pages"`
+}
@@ -0,0 +1,54 @@
+package dto
+
+import "time"
+
+// ErasureRequestCreateRequest needs the current password, except from users
+// linked to an identity provider who signed in again recently.
+type ErasureRequestCreateRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Reason          string `json:"reason" validate:"max=1000"`
+}
+
+type ErasureRequestRejectRequest struct {
+	Note string `json:"note" validate:"required,max=1000"`
+}
+
+type ErasureRequestResponse struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason"`
+	Note        string     `json:"note"`
+	ProcessedAt *time.Time `json:"processed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type AdminErasureRequestResponse struct {
+	ErasureRequestResponse
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type RentalExport struct {
+	ID           int        `json:"id"`
+	BookID       int        `json:"book_id"`
+	TotalPrice   float64    `json:"total_price"`
+	LateFee      float64    `json:"late_fee"`
+	TotalCharge  *float64   `json:"total_charge"`
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      time.Time  `json:"end_date"`
+	ReturnedAt   *time.Time `json:"returned_at"`
+	RenewalCount int        `json:"renewal_count"`
+}
+
+// UserDataExport is everything stored about a user. Rentals carry the rental
+// prices and late fees, which are the only payment records kept.
+type UserDataExport struct {
+	ExportedAt      time.Time                `json:"exported_at"`
+	Profile         UserProfileResponse      `json:"profile"`
+	Rentals         []RentalExport           `json:"rentals"`
+	Reservations    []ReservationResponse    `json:"reservations"`
+	Sessions        []SessionResponse        `json:"sessions"`
+	ApiKeys         []ApiKeyResponse         `json:"api_keys"`
+	ErasureRequests []ErasureRequestResponse `json:"erasure_requests"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+type RentCreateRequest struct {
+	BookID int `json:"book_id" validate:"required"`
+}
+
+type RentCreateResponse struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	BookID     int       `json:"book_id"`
+	TotalPrice float64   `json:"total_price"`
+	StartDate  time.Time `json:"start_date"`
+	EndDate    time.Time `json:"end_date"`
+}
+
+type RentReturnResponse struct {
+	ID          int       `json:"id"`
+	BookID      int       `json:"book_id"`
+	TotalPrice  float64   `json:"total_price"`
+	LateFee     float64   `json:"late_fee"`
+	TotalCharge float64   `json:"total_charge"`
+	EndDate     time.Time `json:"end_date"`
+	ReturnedAt  time.Time `json:"returned_at"`
+}
+
+type RentRenewResponse struct {
+	ID           int       `json:"id"`
+	BookID       int       `json:"book_id"`
+	TotalPrice   float64   `json:"total_price"`
+	EndDate      time.Time `json:"end_date"`
+	RenewalCount int       `json:"renewal_count"`
+}
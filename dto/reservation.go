@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+type ReservationCreateRequest struct {
+	BookID int `json:"book_id" validate:"required"`
+}
+
+type ReservationMoveRequest struct {
+	Position int `json:"position" validate:"required,min=1"`
+}
+
+type ReservationResponse struct {
+	ID            int        `json:"id"`
+	UserID        int        `json:"user_id"`
+	BookID        int        `json:"book_id"`
+	Position      int        `json:"position"`
+	Status        string     `json:"status"`
+	HoldExpiresAt *time.Time `json:"hold_expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -1,20 +1,116 @@
 package dto
 
+import "time"
+
 type UserRegisterRequest struct {
 	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
-	Role     string `json:"role" validate:"required"`
 }
 
 type UserRegisterResponse struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
+	ID            int    `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type UserLoginRequest struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
+
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type UserLogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type AdminUserCreateRequest struct {
+	Username string `json:"username" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	Role     string `json:"role" validate:"required"`
+}
+
+type AdminUserRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type EmailVerificationConfirmRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type EmailVerificationResendRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type UserLoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type UserProfileResponse struct {
+	ID               int       `json:"id"`
+	Username         string    `json:"username"`
+	Email            string    `json:"email"`
+	Role             string    `json:"role"`
+	EmailVerified    bool      `json:"email_verified"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type UserProfileUpdateRequest struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type UserEmailChangeRequest struct {
+	Email           string `json:"email" validate:"required,email"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+}
+
+type UserPasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type AdminUserListQuery struct {
+	Page   int    `query:"page" validate:"omitempty,min=1"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Search string `query:"q"`
+	Role   string `query:"role"`
+	Status string `query:"status" validate:"omitempty,oneof=active suspended unverified"`
+}
+
+type AdminUserResponse struct {
+	ID                    int        `json:"id"`
+	Username              string     `json:"username"`
+	Email                 string     `json:"email"`
+	Role                  string     `json:"role"`
+	Status                string     `json:"status"`
+	EmailVerified         bool       `json:"email_verified"`
+	TwoFactorEnabled      bool       `json:"two_factor_enabled"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	SuspendedAt           *time.Time `json:"suspended_at"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+type AdminUserListResponse struct {
+	Data       []AdminUserResponse `json:"data"`
+	Page       int                 `json:"page"`
+	Limit      int                 `json:"limit"`
+	Total      int                 `json:"total"`
+	TotalPages int                 `json:"total_pages"`
+}
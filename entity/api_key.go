@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ApiKeyPrefix starts every API key, so leaked keys are easy to recognise.
+const ApiKeyPrefix = "dgw_"
+
+// ApiKey lets scripts call the API on behalf of a user without logging in.
+// Only the hash of the key is stored; KeyPrefix identifies it in listings.
+type ApiKey struct {
+	ID         int            `db:"id"`
+	UserID     int            `db:"user_id"`
+	Name       string         `db:"name"`
+	KeyPrefix  string         `db:"key_prefix"`
+	KeyHash    string         `db:"key_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+	LastUsedIP *string        `db:"last_used_ip"`
+	RevokedAt  *time.Time     `db:"revoked_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
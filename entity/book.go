@@ -13,3 +13,9 @@ type Book struct {
 	CreatedAt     time.Time `db:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at"`
 }
+
+type BookSearchResult struct {
+	Book
+	Rank    float64 `db:"rank"`
+	Snippet string  `db:"snippet"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+const (
+	ErasureStatusPending   = "pending"
+	ErasureStatusCompleted = "completed"
+	ErasureStatusRejected  = "rejected"
+)
+
+// ErasureRequest is a request of a user to have their personal data erased.
+// Administrators complete it by anonymizing the account or reject it with a
+// note.
+type ErasureRequest struct {
+	ID          int        `db:"id"`
+	UserID      int        `db:"user_id"`
+	Status      string     `db:"status"`
+	Reason      string     `db:"reason"`
+	Note        string     `db:"note"`
+	ProcessedBy *int       `db:"processed_by"`
+	ProcessedAt *time.Time `db:"processed_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// LoginChallenge is handed out by a login whose password was correct but
+// whose user still has to enter a two-factor code.
+type LoginChallenge struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	Attempts  int        `db:"attempts"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// OIDCLoginState remembers a single sign-on attempt between the redirect to
+// the identity provider and its callback.
+type OIDCLoginState struct {
+	ID           int        `db:"id"`
+	StateHash    string     `db:"state_hash"`
+	Nonce        string     `db:"nonce"`
+	CodeVerifier string     `db:"code_verifier"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	UsedAt       *time.Time `db:"used_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+type Rent struct {
+	ID           int        `db:"id"`
+	UserID       int        `db:"user_id"`
+	BookID       int        `db:"book_id"`
+	TotalPrice   float64    `db:"total_price"`
+	StartDate    time.Time  `db:"start_date"`
+	EndDate      time.Time  `db:"end_date"`
+	ReturnedAt   *time.Time `db:"returned_at"`
+	LateFee      float64    `db:"late_fee"`
+	TotalCharge  *float64   `db:"total_charge"`
+	RenewalCount int        `db:"renewal_count"`
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+const (
+	ReservationStatusWaiting   = "waiting"
+	ReservationStatusHeld      = "held"
+	ReservationStatusFulfilled = "fulfilled"
+	ReservationStatusExpired   = "expired"
+	ReservationStatusCancelled = "cancelled"
+)
+
+type Reservation struct {
+	ID            int        `db:"id"`
+	UserID        int        `db:"user_id"`
+	BookID        int        `db:"book_id"`
+	Position      int        `db:"position"`
+	Status        string     `db:"status"`
+	HoldExpiresAt *time.Time `db:"hold_expires_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+const (
+	PermissionBooksRead          = "books:read"
+	PermissionBooksWrite         = "books:write"
+	PermissionRentsCreate        = "rents:create"
+	PermissionRentsSelf          = "rents:self"
+	PermissionRentsReadAll       = "rents:read_all"
+	PermissionRentsManage        = "rents:manage"
+	PermissionReservationsCreate = "reservations:create"
+	PermissionReservationsSelf   = "reservations:self"
+	PermissionReservationsManage = "reservations:manage"
+	PermissionUsersManage        = "users:manage"
+	PermissionRolesManage        = "roles:manage"
+)
+
+type Role struct {
+	ID          int       `db:"id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+type Permission struct {
+	ID          int    `db:"id"`
+	Name        string `db:"name"`
+	Description string `db:"description"`
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// Session is one login of a user. Its ID is shared by the refresh tokens
+// rotated from that login and carried as the sid claim of access tokens.
+type Session struct {
+	ID                string     `db:"id"`
+	UserID            int        `db:"user_id"`
+	Device            string     `db:"device"`
+	IPAddress         string     `db:"ip_address"`
+	UserAgent         string     `db:"user_agent"`
+	TwoFactorVerified bool       `db:"two_factor_verified"`
+	CreatedAt         time.Time  `db:"created_at"`
+	LastSeenAt        time.Time  `db:"last_seen_at"`
+	RevokedAt         *time.Time `db:"revoked_at"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// SigningKey is a private key used to sign access tokens. A retired key no
+// longer signs new tokens but still verifies the tokens it signed until it
+// expires.
+type SigningKey struct {
+	KID        string     `db:"kid"`
+	Algorithm  string     `db:"algorithm"`
+	PrivateKey string     `db:"private_key"`
+	CreatedAt  time.Time  `db:"created_at"`
+	RetiredAt  *time.Time `db:"retired_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+}
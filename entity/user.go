@@ -2,12 +2,32 @@ package entity
 
 import "time"
 
+const (
+	RoleAdmin    = "Admin"
+	RoleCustomer = "Customer"
+)
+
+const (
+	UserStatusActive     = "active"
+	UserStatusSuspended  = "suspended"
+	UserStatusUnverified = "unverified"
+)
+
 type User struct {
-	ID        int       `db:"id"`
-	Username  string    `db:"username"`
-	Email     string    `db:"email"`
-	Password  string    `db:"password"`
-	Role      string    `db:"role"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID                    int        `db:"id"`
+	Username              string     `db:"username"`
+	Email                 string     `db:"email"`
+	Password              string     `db:"password"`
+	Role                  string     `db:"role"`
+	EmailVerifiedAt       *time.Time `db:"email_verified_at"`
+	TOTPSecret            *string    `db:"totp_secret"`
+	TOTPEnabledAt         *time.Time `db:"totp_enabled_at"`
+	TOTPLastStep          *int64     `db:"totp_last_step"`
+	SuspendedAt           *time.Time `db:"suspended_at"`
+	PasswordResetRequired bool       `db:"password_reset_required"`
+	OIDCIssuer            *string    `db:"oidc_issuer"`
+	OIDCSubject           *string    `db:"oidc_subject"`
+	AnonymizedAt          *time.Time `db:"anonymized_at"`
+	CreatedAt             time.Time  `db:"created_at"`
+	UpdatedAt             time.Time  `db:"updated_at"`
 }
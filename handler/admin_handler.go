@@ -0,0 +1,562 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/config"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/password"
+	"dgw-technical-test/repository"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandler struct {
+	UserRepository          repository.UserRepository
+	RoleRepository          repository.RoleRepository
+	LoginAttemptRepository  repository.LoginAttemptRepository
+	PasswordResetRepository repository.PasswordResetRepository
+	AuthConfig              *config.AuthConfig
+	PasswordChecker         *password.Checker
+	PasswordHasher          password.Hasher
+	Mailer                  mailer.Mailer
+	Validate                *validator.Validate
+}
+
+func NewAdminHandler(userRepository repository.UserRepository, roleRepository repository.RoleRepository, loginAttemptRepository repository.LoginAttemptRepository, passwordResetRepository repository.PasswordResetRepository, authConfig *config.AuthConfig, passwordChecker *password.Checker, passwordHasher password.Hasher, mailer mailer.Mailer, validate *validator.Validate) *AdminHandler {
+	return &AdminHandler{
+		UserRepository:          userRepository,
+		RoleRepository:          roleRepository,
+		LoginAttemptRepository:  loginAttemptRepository,
+		PasswordResetRepository: passwordResetRepository,
+		AuthConfig:              authConfig,
+		PasswordChecker:         passwordChecker,
+		PasswordHasher:          passwordHasher,
+		Mailer:                  mailer,
+		Validate:                validate,
+	}
+}
+
+// @Summary      Create user
+// @Description  Creates a user with any role, including administrators
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.AdminUserCreateRequest  true  "Create Request"
+// @Success      201      {object}  dto.UserRegisterResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users [post]
+// @Security     Bearer
+func (handler *AdminHandler) CreateUser(c *fiber.Ctx) error {
+	requestBody := new(dto.AdminUserCreateRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if _, err := handler.RoleRepository.FindByName(requestBody.Role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid role"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	taken, err := usernameOrEmailTaken(handler.UserRepository, requestBody.Username, requestBody.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if taken {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "username or email already exists"})
+	}
+
+	violations, err := handler.PasswordChecker.Check(requestBody.Password, requestBody.Username, requestBody.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(violations) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(weakPasswordResponse(violations))
+	}
+
+	hashPassword, err := handler.PasswordHasher.Hash(requestBody.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The administrator vouches for the email address.
+	verifiedAt := time.Now()
+	user := &entity.User{
+		Username:        requestBody.Username,
+		Email:           requestBody.Email,
+		Password:        hashPassword,
+		Role:            requestBody.Role,
+		EmailVerifiedAt: &verifiedAt,
+	}
+
+	if err := handler.UserRepository.Register(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := dto.UserRegisterResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: true,
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Successfully created new user",
+		"data":    responseBody,
+	})
+}
+
+// @Summary      Change user role
+// @Description  Promotes or demotes a user; the new role applies from their next login
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.AdminUserRoleRequest  true  "Role Request"
+// @Success      200      {object}  dto.UserRegisterResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users/:id/role [put]
+// @Security     Bearer
+func (handler *AdminHandler) UpdateUserRole(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	requestBody := new(dto.AdminUserRoleRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if _, err := handler.RoleRepository.FindByName(requestBody.Role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid role"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user, err := handler.UserRepository.FindById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.UserRepository.UpdateRole(user.ID, requestBody.Role); err != nil {
+		if errors.Is(err, repository.ErrLastAdmin) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot demote the last admin"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := dto.UserRegisterResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Role:          requestBody.Role,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully updated user role",
+		"data":    responseBody,
+	})
+}
+
+// @Summary      Unlock user
+// @Description  Clears the failed login attempts of a user so they can log in again right away
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users/:id/unlock [post]
+// @Security     Bearer
+func (handler *AdminHandler) UnlockUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	user, err := handler.UserRepository.FindById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.LoginAttemptRepository.Clear(accountAttemptKey(user.Username)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully unlocked user"})
+}
+
+// @Summary      List users
+// @Description  Retrieves one page of users, optionally searched by username or email and filtered by role and status
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        page    query     int     false  "Page number, starting at 1"
+// @Param        limit   query     int     false  "Users per page, at most 100"
+// @Param        q       query     string  false  "Part of the username or email"
+// @Param        role    query     string  false  "Role name"
+// @Param        status  query     string  false  "active, suspended or unverified"
+// @Success      200      {object}  dto.AdminUserListResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users [get]
+// @Security     Bearer
+func (handler *AdminHandler) FindAllUsers(c *fiber.Ctx) error {
+	query := new(dto.AdminUserListQuery)
+
+	if err := c.QueryParser(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultPageLimit
+	}
+
+	filter := repository.UserFilter{
+		Search: query.Search,
+		Role:   query.Role,
+		Status: query.Status,
+		Limit:  query.Limit,
+		Offset: (query.Page - 1) * query.Limit,
+	}
+
+	users, total, err := handler.UserRepository.FindAll(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	data := make([]dto.AdminUserResponse, 0, len(users))
+	for i := range users {
+		data = append(data, newAdminUserResponse(&users[i]))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.AdminUserListResponse{
+		Data:       data,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		Total:      total,
+		TotalPages: (total + query.Limit - 1) / query.Limit,
+	})
+}
+
+// @Summary      Get user
+// @Description  Retrieves a user with their account status
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.AdminUserResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users/:id [get]
+// @Security     Bearer
+func (handler *AdminHandler) FindUserById(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	user, err := handler.UserRepository.FindById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newAdminUserResponse(user))
+}
+
+// @Summary      Suspend user
+// @Description  Blocks a user from logging in and signs them out of every session
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.AdminUserResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users/:id/suspend [post]
+// @Security     Bearer
+func (handler *AdminHandler) SuspendUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	user, err := handler.UserRepository.FindById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	if user.ID == claims.UserID {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot suspend your own account"})
+	}
+
+	if err := handler.UserRepository.Suspend(user.ID); err != nil {
+		if errors.Is(err, repository.ErrLastAdmin) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot suspend the last admin"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	suspendedAt := time.Now()
+	if user.SuspendedAt == nil {
+		user.SuspendedAt = &suspendedAt
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully suspended user",
+		"data":    newAdminUserResponse(user),
+	})
+}
+
+// @Summary      Reactivate user
+// @Description  Lets a suspended user log in again
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.AdminUserResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users/:id/reactivate [post]
+// @Security     Bearer
+func (handler *AdminHandler) ReactivateUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	user, err := handler.UserRepository.FindById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if user.AnonymizedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "user has been erased"})
+	}
+
+	if err := handler.UserRepository.Reactivate(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user.SuspendedAt = nil
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully reactivated user",
+		"data":    newAdminUserResponse(user),
+	})
+}
+
+// @Summary      Force password reset
+// @Description  Signs a user out of every session, blocks their login until they choose a new password and emails them a reset link
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.AdminUserResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users/:id/password-reset [post]
+// @Security     Bearer
+func (handler *AdminHandler) ForcePasswordReset(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	user, err := handler.UserRepository.FindById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.UserRepository.RequirePasswordReset(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user.PasswordResetRequired = true
+
+	if err := sendPasswordResetEmail(handler.PasswordResetRepository, handler.Mailer, handler.AuthConfig, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "password reset is required but the email could not be sent: " + err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully required password reset",
+		"data":    newAdminUserResponse(user),
+	})
+}
+
+// @Summary      Delete user
+// @Description  Deletes a user without rentals or reservations. Users with history can be suspended instead.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/users/:id [delete]
+// @Security     Bearer
+func (handler *AdminHandler) DeleteUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	user, err := handler.UserRepository.FindById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	if user.ID == claims.UserID {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot delete your own account"})
+	}
+
+	if err := handler.UserRepository.Delete(user.ID); err != nil {
+		if errors.Is(err, repository.ErrLastAdmin) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot delete the last admin"})
+		}
+		if errors.Is(err, repository.ErrUserHasHistory) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "user has rentals or reservations, suspend the account instead"})
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully deleted user"})
+}
+
+func newAdminUserResponse(user *entity.User) dto.AdminUserResponse {
+	status := entity.UserStatusActive
+	switch {
+	case user.SuspendedAt != nil:
+		status = entity.UserStatusSuspended
+	case user.EmailVerifiedAt == nil:
+		status = entity.UserStatusUnverified
+	}
+
+	return dto.AdminUserResponse{
+		ID:                    user.ID,
+		Username:              user.Username,
+		Email:                 user.Email,
+		Role:                  user.Role,
+		Status:                status,
+		EmailVerified:         user.EmailVerifiedAt != nil,
+		TwoFactorEnabled:      user.TOTPEnabledAt != nil,
+		PasswordResetRequired: user.PasswordResetRequired,
+		SuspendedAt:           user.SuspendedAt,
+		CreatedAt:             user.CreatedAt,
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/config"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/repository"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyPrefixLength is how much of a key is kept in clear text to tell keys
+// apart in listings.
+const apiKeyPrefixLength = len(entity.ApiKeyPrefix) + 8
+
+type ApiKeyHandler struct {
+	ApiKeyRepository repository.ApiKeyRepository
+	ApiKeyPolicy     *config.ApiKeyPolicy
+	Validate         *validator.Validate
+}
+
+func NewApiKeyHandler(apiKeyRepository repository.ApiKeyRepository, apiKeyPolicy *config.ApiKeyPolicy, validate *validator.Validate) *ApiKeyHandler {
+	return &ApiKeyHandler{
+		ApiKeyRepository: apiKeyRepository,
+		ApiKeyPolicy:     apiKeyPolicy,
+		Validate:         validate,
+	}
+}
+
+// @Summary      Create API key
+// @Description  Creates an API key for the logged in user, limited to the given permissions of their role. The key is only returned once and is sent in the X-API-Key header.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.ApiKeyCreateRequest  true  "API Key Request"
+// @Success      201      {object}  dto.ApiKeyCreateResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/api-keys [post]
+// @Security     Bearer
+func (handler *ApiKeyHandler) Create(c *fiber.Ctx) error {
+	requestBody := new(dto.ApiKeyCreateRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	for _, scope := range requestBody.Scopes {
+		if !claims.HasPermission(scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "cannot grant permission " + scope + " that your role does not have"})
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(handler.ApiKeyPolicy.DefaultLifetime)
+	if requestBody.ExpiresAt != nil {
+		expiresAt = *requestBody.ExpiresAt
+	}
+
+	if !expiresAt.After(now) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "expires_at must be in the future"})
+	}
+
+	if expiresAt.After(now.Add(handler.ApiKeyPolicy.MaxLifetime)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "expires_at must be within " + handler.ApiKeyPolicy.MaxLifetime.String()})
+	}
+
+	active, err := handler.ApiKeyRepository.CountActiveByUserId(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if active >= handler.ApiKeyPolicy.MaxPerUser {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "maximum of " + strconv.Itoa(handler.ApiKeyPolicy.MaxPerUser) + " active API keys reached, revoke one first"})
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	key := entity.ApiKeyPrefix + token
+
+	scopes := slices.Clone(requestBody.Scopes)
+	slices.Sort(scopes)
+
+	apiKey := &entity.ApiKey{
+		UserID:    claims.UserID,
+		Name:      requestBody.Name,
+		KeyPrefix: key[:apiKeyPrefixLength],
+		KeyHash:   hashToken(key),
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: expiresAt,
+	}
+
+	if err := handler.ApiKeyRepository.Create(apiKey); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Successfully created API key, store it now as it cannot be shown again",
+		"data": dto.ApiKeyCreateResponse{
+			ApiKeyResponse: newApiKeyResponse(apiKey),
+			Key:            key,
+		},
+	})
+}
+
+// @Summary      Get my API keys
+// @Description  Lists the API keys of the logged in user that are neither revoked nor expired
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   dto.ApiKeyResponse
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/api-keys [get]
+// @Security     Bearer
+func (handler *ApiKeyHandler) FindMine(c *fiber.Ctx) error {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	apiKeys, err := handler.ApiKeyRepository.FindActiveByUserId(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := make([]dto.ApiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		responseBody = append(responseBody, newApiKeyResponse(&apiKey))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(responseBody)
+}
+
+// @Summary      Revoke API key
+// @Description  Revokes one of the API keys of the logged in user
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/api-keys/:id [delete]
+// @Security     Bearer
+func (handler *ApiKeyHandler) Revoke(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	apiKeyId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid API key id"})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	apiKey, err := handler.ApiKeyRepository.FindById(apiKeyId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if apiKey.UserID != claims.UserID || apiKey.RevokedAt != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+	}
+
+	if err := handler.ApiKeyRepository.Revoke(apiKey.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully revoked API key"})
+}
+
+func newApiKeyResponse(apiKey *entity.ApiKey) dto.ApiKeyResponse {
+	return dto.ApiKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.KeyPrefix,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		LastUsedIP: apiKey.LastUsedIP,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
@@ -11,9 +11,10 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
+const defaultPageLimit = 20
+
 type BookHandler struct {
 	BookRepository repository.BookRepository
 	Validate       *validator.Validate
@@ -36,6 +37,7 @@ func NewBookHandler(bookRepository repository.BookRepository, validate *validato
 // @Success      201      {object}  dto.BookCreateResponse
 // @Failure      400      {object}  map[string]string
 // @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /books [post]
 // @Security     Bearer
@@ -50,17 +52,6 @@ func (handler *BookHandler) Create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	claims, ok := c.Locals("user").(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
-	}
-
-	userRole := claims["role"].(string)
-
-	if userRole != "Admin" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "need admin role to perform this action"})
-	}
-
 	book := &entity.Book{
 		Name:          requestBody.Name,
 		Genre:         requestBody.Genre,
@@ -100,6 +91,7 @@ func (handler *BookHandler) Create(c *fiber.Ctx) error {
 // @Success      200      {object}  map[string]interface{}
 // @Failure      400      {object}  map[string]string
 // @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /books/:id [put]
@@ -122,17 +114,6 @@ func (handler *BookHandler) Update(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	claims, ok := c.Locals("user").(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
-	}
-
-	userRole := claims["role"].(string)
-
-	if userRole != "Admin" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "need admin role to perform this action"})
-	}
-
 	book, err := handler.BookRepository.FindById(bookId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -166,6 +147,7 @@ func (handler *BookHandler) Update(c *fiber.Ctx) error {
 // @Param Authorization header string true "With the bearer started"
 // @Success      200      {object}  map[string]string
 // @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /books/:id [delete]
@@ -178,17 +160,6 @@ func (handler *BookHandler) Delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	claims, ok := c.Locals("user").(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
-	}
-
-	userRole := claims["role"].(string)
-
-	if userRole != "Admin" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "need admin role to perform this action"})
-	}
-
 	if err := handler.BookRepository.Delete(bookId); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "book not found"})
@@ -200,22 +171,121 @@ func (handler *BookHandler) Delete(c *fiber.Ctx) error {
 }
 
 // @Summary      Get all books
-// @Description  Retrieves a list of books
+// @Description  Retrieves a page of books, optionally filtered and sorted
 // @Tags         Books
 // @Accept       json
 // @Produce      json
 // @Param Authorization header string true "With the bearer started"
-// @Success      200      {array}   entity.Book
+// @Param        page       query     int      false  "Page number, starting at 1"
+// @Param        limit      query     int      false  "Books per page, at most 100"
+// @Param        genre      query     string   false  "Genre, matched exactly ignoring case"
+// @Param        author     query     string   false  "Part of the author name"
+// @Param        min_price  query     number   false  "Minimum price"
+// @Param        max_price  query     number   false  "Maximum price"
+// @Param        in_stock   query     boolean  false  "Only books in stock"
+// @Param        sort       query     string   false  "name, price or published_date"
+// @Param        order      query     string   false  "asc or desc"
+// @Success      200      {object}  dto.BookListResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /books [get]
 // @Security     Bearer
 func (handler *BookHandler) FindAll(c *fiber.Ctx) error {
-	books, err := handler.BookRepository.FindAll()
+	query := new(dto.BookListQuery)
+
+	if err := c.QueryParser(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "min_price must not be greater than max_price"})
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultPageLimit
+	}
+
+	filter := repository.BookFilter{
+		Genre:    query.Genre,
+		Author:   query.Author,
+		MinPrice: query.MinPrice,
+		MaxPrice: query.MaxPrice,
+		InStock:  query.InStock,
+		Sort:     query.Sort,
+		Order:    query.Order,
+		Limit:    query.Limit,
+		Offset:   (query.Page - 1) * query.Limit,
+	}
+
+	books, total, err := handler.BookRepository.FindAll(filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(books)
+	return c.Status(fiber.StatusOK).JSON(dto.BookListResponse{
+		Data:       books,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		Total:      total,
+		TotalPages: (total + query.Limit - 1) / query.Limit,
+	})
+}
+
+// @Summary      Search books
+// @Description  Full-text search over the name, author and genre of books
+// @Tags         Books
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        q      query     string  true   "Search text, every word is matched as a prefix"
+// @Param        page   query     int     false  "Page number, starting at 1"
+// @Param        limit  query     int     false  "Books per page, at most 100"
+// @Success      200      {object}  dto.BookSearchResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /books/search [get]
+// @Security     Bearer
+func (handler *BookHandler) Search(c *fiber.Ctx) error {
+	query := new(dto.BookSearchQuery)
+
+	if err := c.QueryParser(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultPageLimit
+	}
+
+	results, total, err := handler.BookRepository.Search(query.Query, query.Limit, (query.Page-1)*query.Limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.BookSearchResponse{
+		Data:       results,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		Total:      total,
+		TotalPages: (total + query.Limit - 1) / query.Limit,
+	})
 }
 
 // @Summary      Get book by id
@@ -225,6 +295,7 @@ func (handler *BookHandler) FindAll(c *fiber.Ctx) error {
 // @Produce      json
 // @Param Authorization header string true "With the bearer started"
 // @Success      200      {object}  entity.Book
+// @Failure      403      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /books/:id [get]
@@ -0,0 +1,58 @@
+package handler
+
+import "strings"
+
+// describeDevice returns a short label of the client, e.g. "Firefox on
+// Windows". A name sent by the client itself wins over the user agent.
+func describeDevice(deviceName string, userAgent string) string {
+	if name := strings.TrimSpace(deviceName); name != "" {
+		if len(name) > 100 {
+			name = name[:100]
+		}
+		return name
+	}
+
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := firstMatch(userAgent, [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	})
+
+	system := firstMatch(userAgent, [][2]string{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	})
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+func firstMatch(userAgent string, candidates [][2]string) string {
+	for _, candidate := range candidates {
+		if strings.Contains(userAgent, candidate[0]) {
+			return candidate[1]
+		}
+	}
+
+	return ""
+}
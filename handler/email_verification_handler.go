@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/config"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/repository"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailVerificationHandler struct {
+	UserRepository              repository.UserRepository
+	EmailVerificationRepository repository.EmailVerificationRepository
+	AuthConfig                  *config.AuthConfig
+	Mailer                      mailer.Mailer
+	Validate                    *validator.Validate
+}
+
+func NewEmailVerificationHandler(userRepository repository.UserRepository, emailVerificationRepository repository.EmailVerificationRepository, authConfig *config.AuthConfig, mailer mailer.Mailer, validate *validator.Validate) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		UserRepository:              userRepository,
+		EmailVerificationRepository: emailVerificationRepository,
+		AuthConfig:                  authConfig,
+		Mailer:                      mailer,
+		Validate:                    validate,
+	}
+}
+
+// @Summary      Verify email
+// @Description  Confirms the email address of a user with the token from the verification email
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.EmailVerificationConfirmRequest  true  "Verification Request"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/verify-email [post]
+func (handler *EmailVerificationHandler) Confirm(c *fiber.Ctx) error {
+	requestBody := new(dto.EmailVerificationConfirmRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	invalidToken := fiber.Map{"error": "invalid or expired verification token"}
+
+	token, err := handler.EmailVerificationRepository.FindByHash(hashToken(requestBody.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusBadRequest).JSON(invalidToken)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(invalidToken)
+	}
+
+	if err := handler.EmailVerificationRepository.Consume(token); err != nil {
+		if errors.Is(err, repository.ErrVerificationTokenUsed) {
+			return c.Status(fiber.StatusBadRequest).JSON(invalidToken)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully verified email"})
+}
+
+// @Summary      Resend verification email
+// @Description  Sends a new verification email, at most once per resend interval. The response is the same whether or not the email is registered.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.EmailVerificationResendRequest  true  "Resend Request"
+// @Success      202      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/verify-email/resend [post]
+func (handler *EmailVerificationHandler) Resend(c *fiber.Ctx) error {
+	requestBody := new(dto.EmailVerificationResendRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	accepted := fiber.Map{"message": "If the email is registered and not verified yet, a verification link has been sent"}
+
+	user, err := handler.UserRepository.FindUserByEmail(requestBody.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusAccepted).JSON(accepted)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return c.Status(fiber.StatusAccepted).JSON(accepted)
+	}
+
+	latest, err := handler.EmailVerificationRepository.FindLatestByUserId(user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Resending too often is silently ignored rather than reported, which
+	// would reveal that the email is registered.
+	if latest != nil && time.Since(latest.CreatedAt) < handler.AuthConfig.EmailVerificationResendInterval {
+		return c.Status(fiber.StatusAccepted).JSON(accepted)
+	}
+
+	if err := sendVerificationEmail(handler.EmailVerificationRepository, handler.Mailer, handler.AuthConfig, user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v\n", user.ID, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(accepted)
+}
+
+// sendVerificationEmail stores a new verification token for the user and
+// mails the link that confirms it.
+func sendVerificationEmail(emailVerificationRepository repository.EmailVerificationRepository, mailSender mailer.Mailer, authConfig *config.AuthConfig, user *entity.User) error {
+	verificationToken, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	token := &entity.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(verificationToken),
+		ExpiresAt: time.Now().Add(authConfig.EmailVerificationTTL),
+	}
+
+	if err := emailVerificationRepository.Create(token); err != nil {
+		return err
+	}
+
+	return mailSender.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to verify your email address. It expires in %s.\n\n%s?token=%s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.Username, authConfig.EmailVerificationTTL, authConfig.EmailVerificationURL, url.QueryEscape(verificationToken)),
+	})
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"dgw-technical-test/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSHandler struct {
+	KeyManager *auth.KeyManager
+}
+
+func NewJWKSHandler(keyManager *auth.KeyManager) *JWKSHandler {
+	return &JWKSHandler{KeyManager: keyManager}
+}
+
+// @Summary      JSON Web Key Set
+// @Description  Public keys that verify the access tokens issued by this service
+// @Tags         Auth
+// @Produce      json
+// @Success      200      {object}  auth.JWKSet
+// @Router       /.well-known/jwks.json [get]
+func (handler *JWKSHandler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(handler.KeyManager.JWKS())
+}
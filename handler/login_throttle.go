@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"dgw-technical-test/config"
+	"dgw-technical-test/repository"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func accountAttemptKey(username string) string {
+	return "account:" + username
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+func tooManyLoginAttempts(c *fiber.Ctx, lockedUntil time.Time) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(lockedUntil).Seconds()))))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many failed login attempts, try again later"})
+}
+
+// recordFailedLogin counts the failure against key and locks the key out
+// once it reached threshold.
+func recordFailedLogin(loginAttemptRepository repository.LoginAttemptRepository, policy *config.LoginPolicy, key string, threshold int) error {
+	now := time.Now()
+
+	failures, err := loginAttemptRepository.RecordFailure(key, now, now.Add(-policy.FailureWindow))
+	if err != nil {
+		return err
+	}
+
+	if failures < threshold {
+		return nil
+	}
+
+	return loginAttemptRepository.Lock(key, now.Add(lockoutDuration(policy, failures-threshold)))
+}
+
+// lockoutDuration doubles the base lockout for every failure past the
+// threshold, up to the maximum lockout.
+func lockoutDuration(policy *config.LoginPolicy, extraFailures int) time.Duration {
+	lockout := policy.BaseLockout
+	for i := 0; i < extraFailures && lockout < policy.MaxLockout; i++ {
+		lockout *= 2
+	}
+
+	return min(lockout, policy.MaxLockout)
+}
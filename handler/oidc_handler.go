@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/auth"
+	"dgw-technical-test/config"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/repository"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	errOIDCEmailMissing = errors.New("the identity provider did not share an email address")
+	errOIDCEmailTaken   = errors.New("an account with this email already exists and the identity provider has not verified the address")
+)
+
+// OIDCHandler signs users in through an OpenID Connect provider and hands
+// out the same tokens as a password login through UserHandler.
+type OIDCHandler struct {
+	UserHandler    *UserHandler
+	OIDCProvider   *auth.OIDCProvider
+	OIDCRepository repository.OIDCRepository
+	OIDCConfig     *config.OIDCConfig
+}
+
+func NewOIDCHandler(userHandler *UserHandler, oidcProvider *auth.OIDCProvider, oidcRepository repository.OIDCRepository, oidcConfig *config.OIDCConfig) *OIDCHandler {
+	return &OIDCHandler{
+		UserHandler:    userHandler,
+		OIDCProvider:   oidcProvider,
+		OIDCRepository: oidcRepository,
+		OIDCConfig:     oidcConfig,
+	}
+}
+
+// @Summary      Single sign-on login
+// @Description  Redirects to the identity provider to sign in with the authorization code flow and PKCE. The provider sends the user back to /users/oidc/callback.
+// @Tags         Users
+// @Produce      json
+// @Success      302
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Failure      502      {object}  map[string]string
+// @Router       /users/oidc/login [get]
+func (handler *OIDCHandler) Login(c *fiber.Ctx) error {
+	if !handler.OIDCConfig.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "single sign-on is not configured"})
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := generateToken()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		values[i] = value
+	}
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	authURL, err := handler.OIDCProvider.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "identity provider is unavailable: " + err.Error()})
+	}
+
+	loginState := &entity.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(handler.OIDCConfig.StateTTL),
+	}
+
+	if err := handler.OIDCRepository.CreateState(loginState); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// @Summary      Single sign-on callback
+// @Description  Completes a single sign-on login. First-time users are created from their identity, and their role follows the OIDC_ROLE_MAPPING of their groups. Returns the same tokens as /users/login, or a two-factor challenge when the provider did not use multiple factors and the user enabled two-factor authentication.
+// @Tags         Users
+// @Produce      json
+// @Param        code   query     string  true  "Authorization code"
+// @Param        state  query     string  true  "State from /users/oidc/login"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Failure      502      {object}  map[string]string
+// @Router       /users/oidc/callback [get]
+func (handler *OIDCHandler) Callback(c *fiber.Ctx) error {
+	if !handler.OIDCConfig.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "single sign-on is not configured"})
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "identity provider refused the login: " + providerError})
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code and state are required"})
+	}
+
+	loginState, err := handler.OIDCRepository.ConsumeState(hashToken(state))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid or expired login state, sign in again"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	identity, err := handler.OIDCProvider.Exchange(code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidIDToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "failed to complete login with the identity provider: " + err.Error()})
+	}
+
+	user, err := handler.findOrProvisionUser(identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, errOIDCEmailMissing):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, errOIDCEmailTaken), errors.Is(err, repository.ErrIdentityLinked):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if reason := loginBlockedReason(user); reason != "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": reason})
+	}
+
+	// The identity provider owns the roles of its users once a mapping is
+	// configured; without one, roles are managed locally.
+	if len(handler.OIDCConfig.RoleMappings) > 0 {
+		if err := handler.syncRole(user, handler.OIDCConfig.RoleFor(identity.Groups)); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if user.TOTPEnabledAt != nil && !identity.MultiFactor {
+		responseBody, err := handler.UserHandler.startTwoFactorChallenge(user)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(responseBody)
+	}
+
+	tokens, err := handler.UserHandler.startSession(c, user, identity.MultiFactor)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens["message"] = "Successfully login"
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// syncRole gives the user the role mapped from their groups. The last admin
+// keeps their role, so a missing group mapping cannot lock everyone out of
+// the admin endpoints.
+func (handler *OIDCHandler) syncRole(user *entity.User, role string) error {
+	if role == user.Role {
+		return nil
+	}
+
+	if err := handler.UserHandler.UserRepository.UpdateRole(user.ID, role); err != nil {
+		if errors.Is(err, repository.ErrLastAdmin) {
+			log.Printf("not demoting user %d to %s through single sign-on: they are the last admin\n", user.ID, role)
+			return nil
+		}
+		return err
+	}
+
+	user.Role = role
+	return nil
+}
+
+// findOrProvisionUser returns the user linked to the identity. Unlinked
+// identities are linked to the account with the same email when the
+// provider verified that address, and get a new account otherwise.
+func (handler *OIDCHandler) findOrProvisionUser(identity *auth.OIDCIdentity) (*entity.User, error) {
+	userRepository := handler.UserHandler.UserRepository
+
+	user, err := handler.OIDCRepository.FindUserBySubject(identity.Issuer, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, errOIDCEmailMissing
+	}
+
+	user, err = userRepository.FindUserByEmail(identity.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if user != nil {
+		if !identity.EmailVerified {
+			return nil, errOIDCEmailTaken
+		}
+
+		if err := handler.OIDCRepository.LinkUser(user.ID, identity.Issuer, identity.Subject); err != nil {
+			return nil, err
+		}
+
+		return user, nil
+	}
+
+	username, err := handler.availableUsername(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// Provisioned users sign in through the provider and never learn this
+	// password; they can choose one with a password reset.
+	randomPassword, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hashPassword, err := handler.UserHandler.PasswordHasher.Hash(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	user = &entity.User{
+		Username:    username,
+		Email:       identity.Email,
+		Password:    hashPassword,
+		Role:        handler.OIDCConfig.RoleFor(identity.Groups),
+		OIDCIssuer:  &identity.Issuer,
+		OIDCSubject: &identity.Subject,
+	}
+
+	if identity.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := userRepository.Register(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// availableUsername derives a username from the identity, numbering it when
+// the name is taken.
+func (handler *OIDCHandler) availableUsername(identity *auth.OIDCIdentity) (string, error) {
+	base := strings.TrimSpace(identity.PreferredUsername)
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	for i := 1; i <= 100; i++ {
+		username := base
+		if i > 1 {
+			username = base + strconv.Itoa(i)
+		}
+
+		if _, err := handler.UserHandler.UserRepository.FindUserByUsername(username); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return username, nil
+			}
+			return "", err
+		}
+	}
+
+	suffix, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	return base + "-" + suffix[:8], nil
+}
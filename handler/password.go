@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// weakPasswordResponse lists every password rule that was broken, so a
+// client can show them all at once.
+func weakPasswordResponse(violations []string) fiber.Map {
+	return fiber.Map{
+		"error":      "password does not meet the policy: " + strings.Join(violations, "; "),
+		"violations": violations,
+	}
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/config"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/password"
+	"dgw-technical-test/repository"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PasswordResetHandler struct {
+	UserRepository          repository.UserRepository
+	PasswordResetRepository repository.PasswordResetRepository
+	LoginAttemptRepository  repository.LoginAttemptRepository
+	AuthConfig              *config.AuthConfig
+	PasswordChecker         *password.Checker
+	PasswordHasher          password.Hasher
+	Mailer                  mailer.Mailer
+	Validate                *validator.Validate
+}
+
+func NewPasswordResetHandler(userRepository repository.UserRepository, passwordResetRepository repository.PasswordResetRepository, loginAttemptRepository repository.LoginAttemptRepository, authConfig *config.AuthConfig, passwordChecker *password.Checker, passwordHasher password.Hasher, mailer mailer.Mailer, validate *validator.Validate) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		UserRepository:          userRepository,
+		PasswordResetRepository: passwordResetRepository,
+		LoginAttemptRepository:  loginAttemptRepository,
+		AuthConfig:              authConfig,
+		PasswordChecker:         passwordChecker,
+		PasswordHasher:          passwordHasher,
+		Mailer:                  mailer,
+		Validate:                validate,
+	}
+}
+
+// @Summary      Request password reset
+// @Description  Emails a single-use password reset link. The response is the same whether or not the email is registered.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.PasswordResetRequest  true  "Reset Request"
+// @Success      202      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/password-reset [post]
+func (handler *PasswordResetHandler) Request(c *fiber.Ctx) error {
+	requestBody := new(dto.PasswordResetRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	accepted := fiber.Map{"message": "If the email is registered, a password reset link has been sent"}
+
+	user, err := handler.UserRepository.FindUserByEmail(requestBody.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusAccepted).JSON(accepted)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// A delivery failure is not reported to the client, since it would
+	// reveal that the email is registered.
+	if err := sendPasswordResetEmail(handler.PasswordResetRepository, handler.Mailer, handler.AuthConfig, user); err != nil {
+		log.Printf("failed to send password reset email to user %d: %v\n", user.ID, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(accepted)
+}
+
+// @Summary      Confirm password reset
+// @Description  Sets a new password with a reset token and signs the user out of every session
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.PasswordResetConfirmRequest  true  "Confirm Request"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/password-reset/confirm [post]
+func (handler *PasswordResetHandler) Confirm(c *fiber.Ctx) error {
+	requestBody := new(dto.PasswordResetConfirmRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	invalidToken := fiber.Map{"error": "invalid or expired reset token"}
+
+	token, err := handler.PasswordResetRepository.FindByHash(hashToken(requestBody.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusBadRequest).JSON(invalidToken)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(invalidToken)
+	}
+
+	user, err := handler.UserRepository.FindById(token.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	violations, err := handler.PasswordChecker.Check(requestBody.Password, user.Username, user.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(violations) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(weakPasswordResponse(violations))
+	}
+
+	hashPassword, err := handler.PasswordHasher.Hash(requestBody.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.PasswordResetRepository.Consume(token, hashPassword); err != nil {
+		if errors.Is(err, repository.ErrResetTokenUsed) {
+			return c.Status(fiber.StatusBadRequest).JSON(invalidToken)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Proving control of the email is enough to lift a lockout.
+	if err := handler.LoginAttemptRepository.Clear(accountAttemptKey(user.Username)); err != nil {
+		log.Printf("failed to clear login attempts of user %d: %v\n", user.ID, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully reset password"})
+}
+
+// sendPasswordResetEmail stores a new reset token for the user and mails the
+// link that uses it.
+func sendPasswordResetEmail(passwordResetRepository repository.PasswordResetRepository, mailSender mailer.Mailer, authConfig *config.AuthConfig, user *entity.User) error {
+	resetToken, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	token := &entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(resetToken),
+		ExpiresAt: time.Now().Add(authConfig.PasswordResetTTL),
+	}
+
+	if err := passwordResetRepository.Create(token); err != nil {
+		return err
+	}
+
+	return mailSender.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and works once.\n\n%s?token=%s\n\nIf you did not ask for a password reset, you can ignore this email.\n",
+			user.Username, authConfig.PasswordResetTTL, authConfig.PasswordResetURL, url.QueryEscape(resetToken)),
+	})
+}
@@ -0,0 +1,437 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PrivacyHandler lets users export their data and ask for its erasure, and
+// lets administrators process erasure requests.
+type PrivacyHandler struct {
+	UserHandler           *UserHandler
+	RentRepository        repository.RentRepository
+	ReservationRepository repository.ReservationRepository
+	ApiKeyRepository      repository.ApiKeyRepository
+	ErasureRepository     repository.ErasureRepository
+}
+
+func NewPrivacyHandler(userHandler *UserHandler, rentRepository repository.RentRepository, reservationRepository repository.ReservationRepository, apiKeyRepository repository.ApiKeyRepository, erasureRepository repository.ErasureRepository) *PrivacyHandler {
+	return &PrivacyHandler{
+		UserHandler:           userHandler,
+		RentRepository:        rentRepository,
+		ReservationRepository: reservationRepository,
+		ApiKeyRepository:      apiKeyRepository,
+		ErasureRepository:     erasureRepository,
+	}
+}
+
+// @Summary      Export my data
+// @Description  Downloads everything stored about the logged in user: profile, rentals with their prices and late fees, reservations, sessions, API keys and erasure requests. format=zip returns one JSON file per section in a ZIP archive.
+// @Tags         Users
+// @Produce      json
+// @Produce      application/zip
+// @Param Authorization header string true "With the bearer started"
+// @Param        format  query     string  false  "json (default) or zip"
+// @Success      200      {object}  dto.UserDataExport
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/me/export [get]
+// @Security     Bearer
+func (handler *PrivacyHandler) ExportMine(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "zip" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be json or zip"})
+	}
+
+	user, err := handler.UserHandler.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	export, err := handler.collectUserData(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filename := fmt.Sprintf("user-%d-export-%s", user.ID, export.ExportedAt.Format("20060102-150405"))
+
+	if format == "json" {
+		body, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		c.Attachment(filename + ".json")
+		c.Type("json")
+		return c.Status(fiber.StatusOK).Send(body)
+	}
+
+	body, err := zipUserData(export)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Attachment(filename + ".zip")
+	c.Type("zip")
+	return c.Status(fiber.StatusOK).Send(body)
+}
+
+// @Summary      Request erasure of my data
+// @Description  Asks the administrators to erase the personal data of the logged in user after checking the current password. Users linked to an identity provider may leave the password out when they signed in within REAUTHENTICATION_MAX_AGE. Once completed, the account is anonymized and can no longer be used; rentals are kept without personal data.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.ErasureRequestCreateRequest  true  "Erasure Request"
+// @Success      202      {object}  dto.ErasureRequestResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/me/erasure [post]
+// @Security     Bearer
+func (handler *PrivacyHandler) RequestErasure(c *fiber.Ctx) error {
+	requestBody := new(dto.ErasureRequestCreateRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.UserHandler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user, err := handler.UserHandler.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if requestBody.CurrentPassword == "" {
+		if user.OIDCSubject == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "current_password is required"})
+		}
+
+		if !handler.UserHandler.signedInRecently(c, user) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "sign in again through single sign-on, or give the current password, to request erasure"})
+		}
+	} else {
+		valid, lockedUntil, err := handler.UserHandler.checkCurrentPassword(c, user, requestBody.CurrentPassword)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if lockedUntil != nil {
+			return tooManyLoginAttempts(c, *lockedUntil)
+		}
+
+		if !valid {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "current password is incorrect"})
+		}
+	}
+
+	erasureRequest := &entity.ErasureRequest{
+		UserID: user.ID,
+		Reason: requestBody.Reason,
+	}
+
+	if err := handler.ErasureRepository.Create(erasureRequest); err != nil {
+		if errors.Is(err, repository.ErrErasurePending) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "an erasure request is already pending"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Successfully requested erasure, an administrator will process it",
+		"data":    newErasureRequestResponse(erasureRequest),
+	})
+}
+
+// @Summary      Get erasure requests
+// @Description  Lists the erasure requests with the given status, oldest first
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        status  query     string  false  "pending (default), completed or rejected"
+// @Success      200      {array}   dto.AdminErasureRequestResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/erasure-requests [get]
+// @Security     Bearer
+func (handler *PrivacyHandler) FindErasureRequests(c *fiber.Ctx) error {
+	status := c.Query("status", entity.ErasureStatusPending)
+
+	switch status {
+	case entity.ErasureStatusPending, entity.ErasureStatusCompleted, entity.ErasureStatusRejected:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "status must be pending, completed or rejected"})
+	}
+
+	erasureRequests, err := handler.ErasureRepository.FindByStatus(status)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := make([]dto.AdminErasureRequestResponse, 0, len(erasureRequests))
+	for _, erasureRequest := range erasureRequests {
+		user, err := handler.UserHandler.UserRepository.FindById(erasureRequest.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		responseBody = append(responseBody, dto.AdminErasureRequestResponse{
+			ErasureRequestResponse: newErasureRequestResponse(&erasureRequest),
+			Username:               user.Username,
+			Email:                  user.Email,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(responseBody)
+}
+
+// @Summary      Complete erasure request
+// @Description  Anonymizes the user of a pending erasure request. Users with unreturned rentals, active reservations or who are the last admin cannot be erased yet.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/erasure-requests/:id/complete [post]
+// @Security     Bearer
+func (handler *PrivacyHandler) CompleteErasure(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	requestId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid erasure request id"})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	erasureRequest, err := handler.ErasureRepository.FindById(requestId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "erasure request not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if erasureRequest.Status != entity.ErasureStatusPending {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "erasure request has already been processed"})
+	}
+
+	if erasureRequest.UserID == claims.UserID {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot erase your own account, another admin has to"})
+	}
+
+	if err := handler.ErasureRepository.Complete(erasureRequest.ID, claims.UserID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrErasureClosed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "erasure request has already been processed"})
+		case errors.Is(err, repository.ErrLastAdmin):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot erase the last admin"})
+		case errors.Is(err, repository.ErrErasureActiveRentals), errors.Is(err, repository.ErrErasureActiveReservations):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully erased user"})
+}
+
+// @Summary      Reject erasure request
+// @Description  Rejects a pending erasure request with a note for the user
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.ErasureRequestRejectRequest  true  "Reject Request"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/erasure-requests/:id/reject [post]
+// @Security     Bearer
+func (handler *PrivacyHandler) RejectErasure(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	requestId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid erasure request id"})
+	}
+
+	requestBody := new(dto.ErasureRequestRejectRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.UserHandler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	if _, err := handler.ErasureRepository.FindById(requestId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "erasure request not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.ErasureRepository.Reject(requestId, claims.UserID, requestBody.Note); err != nil {
+		if errors.Is(err, repository.ErrErasureClosed) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "erasure request has already been processed"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully rejected erasure request"})
+}
+
+func (handler *PrivacyHandler) collectUserData(user *entity.User) (*dto.UserDataExport, error) {
+	rents, err := handler.RentRepository.FindByUserId(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations, err := handler.ReservationRepository.FindByUserId(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := handler.UserHandler.SessionRepository.FindByUserId(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := handler.ApiKeyRepository.FindByUserId(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	erasureRequests, err := handler.ErasureRepository.FindByUserId(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &dto.UserDataExport{
+		ExportedAt:      time.Now().UTC(),
+		Profile:         newUserProfileResponse(user),
+		Rentals:         make([]dto.RentalExport, 0, len(rents)),
+		Reservations:    newReservationResponses(reservations),
+		Sessions:        make([]dto.SessionResponse, 0, len(sessions)),
+		ApiKeys:         make([]dto.ApiKeyResponse, 0, len(apiKeys)),
+		ErasureRequests: make([]dto.ErasureRequestResponse, 0, len(erasureRequests)),
+	}
+
+	for _, rent := range rents {
+		export.Rentals = append(export.Rentals, dto.RentalExport{
+			ID:           rent.ID,
+			BookID:       rent.BookID,
+			TotalPrice:   rent.TotalPrice,
+			LateFee:      rent.LateFee,
+			TotalCharge:  rent.TotalCharge,
+			StartDate:    rent.StartDate,
+			EndDate:      rent.EndDate,
+			ReturnedAt:   rent.ReturnedAt,
+			RenewalCount: rent.RenewalCount,
+		})
+	}
+
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, newSessionResponse(&session, ""))
+	}
+
+	for _, apiKey := range apiKeys {
+		export.ApiKeys = append(export.ApiKeys, newApiKeyResponse(&apiKey))
+	}
+
+	for _, erasureRequest := range erasureRequests {
+		export.ErasureRequests = append(export.ErasureRequests, newErasureRequestResponse(&erasureRequest))
+	}
+
+	return export, nil
+}
+
+// zipUserData writes every section of the export to its own JSON file.
+func zipUserData(export *dto.UserDataExport) ([]byte, error) {
+	sections := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"rentals.json", export.Rentals},
+		{"reservations.json", export.Reservations},
+		{"sessions.json", export.Sessions},
+		{"api_keys.json", export.ApiKeys},
+		{"erasure_requests.json", export.ErasureRequests},
+	}
+
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	for _, section := range sections {
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: section.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newErasureRequestResponse(erasureRequest *entity.ErasureRequest) dto.ErasureRequestResponse {
+	return dto.ErasureRequestResponse{
+		ID:          erasureRequest.ID,
+		UserID:      erasureRequest.UserID,
+		Status:      erasureRequest.Status,
+		Reason:      erasureRequest.Reason,
+		Note:        erasureRequest.Note,
+		ProcessedAt: erasureRequest.ProcessedAt,
+		CreatedAt:   erasureRequest.CreatedAt,
+	}
+}
@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/config"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/notification"
+	"dgw-technical-test/repository"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RentHandler struct {
+	RentRepository        repository.RentRepository
+	BookRepository        repository.BookRepository
+	ReservationRepository repository.ReservationRepository
+	RentPolicy            *config.RentPolicy
+	ReservationPolicy     *config.ReservationPolicy
+	Notifier              notification.Notifier
+	Validate              *validator.Validate
+}
+
+func NewRentHandler(rentRepository repository.RentRepository, bookRepository repository.BookRepository, reservationRepository repository.ReservationRepository, rentPolicy *config.RentPolicy, reservationPolicy *config.ReservationPolicy, notifier notification.Notifier, validate *validator.Validate) *RentHandler {
+	return &RentHandler{
+		RentRepository:        rentRepository,
+		BookRepository:        bookRepository,
+		ReservationRepository: reservationRepository,
+		RentPolicy:            rentPolicy,
+		ReservationPolicy:     reservationPolicy,
+		Notifier:              notifier,
+		Validate:              validate,
+	}
+}
+
+// @Summary      Rent book
+// @Description  Rent a book for the logged in customer
+// @Tags         Rents
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.RentCreateRequest  true  "Rent Request"
+// @Success      201      {object}  dto.RentCreateResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /rents [post]
+// @Security     Bearer
+func (handler *RentHandler) Create(c *fiber.Ctx) error {
+	requestBody := new(dto.RentCreateRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	book, err := handler.BookRepository.FindById(requestBody.BookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "book not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	startDate := time.Now()
+	rent := &entity.Rent{
+		UserID:     claims.UserID,
+		BookID:     book.ID,
+		TotalPrice: book.Price * float64(handler.RentPolicy.DurationDays),
+		StartDate:  startDate,
+		EndDate:    startDate.AddDate(0, 0, handler.RentPolicy.DurationDays),
+	}
+
+	if err := handler.RentRepository.Create(rent); err != nil {
+		if errors.Is(err, repository.ErrOutOfStock) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "book is out of stock"})
+		}
+		if errors.Is(err, repository.ErrAccountErased) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "account has been erased"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := dto.RentCreateResponse{
+		ID:         rent.ID,
+		UserID:     rent.UserID,
+		BookID:     rent.BookID,
+		TotalPrice: rent.TotalPrice,
+		StartDate:  rent.StartDate,
+		EndDate:    rent.EndDate,
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Successfully rented book",
+		"data":    responseBody,
+	})
+}
+
+// @Summary      Return book
+// @Description  Return a rented book and settle the late fee, if any
+// @Tags         Rents
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.RentReturnResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /rents/:id/return [post]
+// @Security     Bearer
+func (handler *RentHandler) Return(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	rentId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid rent id"})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	rent, err := handler.RentRepository.FindById(rentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rent not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if rent.UserID != claims.UserID && !claims.HasPermission(entity.PermissionRentsManage) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rent not found"})
+	}
+
+	if rent.ReturnedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "rent has already been returned"})
+	}
+
+	returnedAt := time.Now()
+	rent.ReturnedAt = &returnedAt
+	rent.LateFee = calculateLateFee(handler.RentPolicy, rent.EndDate, returnedAt)
+	totalCharge := rent.TotalPrice + rent.LateFee
+	rent.TotalCharge = &totalCharge
+
+	held, err := handler.RentRepository.Return(rent, returnedAt.Add(handler.ReservationPolicy.HoldDuration))
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyReturned) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "rent has already been returned"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if held != nil {
+		if err := handler.Notifier.ReservationHeld(*held); err != nil {
+			log.Printf("failed to notify reservation %d: %v\n", held.ID, err)
+		}
+	}
+
+	responseBody := dto.RentReturnResponse{
+		ID:          rent.ID,
+		BookID:      rent.BookID,
+		TotalPrice:  rent.TotalPrice,
+		LateFee:     rent.LateFee,
+		TotalCharge: totalCharge,
+		EndDate:     rent.EndDate,
+		ReturnedAt:  returnedAt,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully returned book",
+		"data":    responseBody,
+	})
+}
+
+// @Summary      Renew rent
+// @Description  Extend the end date of an active rent that is not overdue and has nobody waiting for the book
+// @Tags         Rents
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.RentRenewResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /rents/:id/renew [post]
+// @Security     Bearer
+func (handler *RentHandler) Renew(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	rentId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid rent id"})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	rent, err := handler.RentRepository.FindById(rentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rent not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if rent.UserID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rent not found"})
+	}
+
+	if rent.ReturnedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "rent has already been returned"})
+	}
+
+	if time.Now().After(rent.EndDate) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "rent is overdue and cannot be renewed, return the book instead"})
+	}
+
+	if rent.RenewalCount >= handler.RentPolicy.MaxRenewals {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "rent has reached the maximum number of renewals"})
+	}
+
+	book, err := handler.BookRepository.FindById(rent.BookID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	previousRenewalCount := rent.RenewalCount
+	rent.EndDate = rent.EndDate.AddDate(0, 0, handler.RentPolicy.RenewalDays)
+	rent.TotalPrice += book.Price * float64(handler.RentPolicy.RenewalDays)
+	rent.RenewalCount++
+
+	if err := handler.RentRepository.Renew(rent, previousRenewalCount); err != nil {
+		if errors.Is(err, repository.ErrBookWaitlisted) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "other customers are waiting for this book"})
+		}
+		if errors.Is(err, repository.ErrRentChanged) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "rent was modified, please try again"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := dto.RentRenewResponse{
+		ID:           rent.ID,
+		BookID:       rent.BookID,
+		TotalPrice:   rent.TotalPrice,
+		EndDate:      rent.EndDate,
+		RenewalCount: rent.RenewalCount,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully renewed rent",
+		"data":    responseBody,
+	})
+}
+
+// @Summary      Get my active rents
+// @Description  Retrieves the active rents of the logged in user
+// @Tags         Rents
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   entity.Rent
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /rents/me [get]
+// @Security     Bearer
+func (handler *RentHandler) FindMine(c *fiber.Ctx) error {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	rents, err := handler.RentRepository.FindActiveByUserId(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rents)
+}
+
+// @Summary      Get all rents
+// @Description  Retrieves a list of every rent
+// @Tags         Rents
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   entity.Rent
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /rents [get]
+// @Security     Bearer
+func (handler *RentHandler) FindAll(c *fiber.Ctx) error {
+	rents, err := handler.RentRepository.FindAll()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rents)
+}
+
+// calculateLateFee charges the per-day rate for every started day a book is
+// returned past its end date, ignoring the grace period and never exceeding
+// the configured cap. A cap of zero means the fee is unbounded.
+func calculateLateFee(policy *config.RentPolicy, endDate, returnedAt time.Time) float64 {
+	overdue := returnedAt.Sub(endDate) - time.Duration(policy.LateFeeGraceDays)*24*time.Hour
+	if overdue <= 0 {
+		return 0
+	}
+
+	days := math.Ceil(overdue.Hours() / 24)
+	fee := days * policy.LateFeePerDay
+
+	if policy.LateFeeCap > 0 && fee > policy.LateFeeCap {
+		fee = policy.LateFeeCap
+	}
+
+	return fee
+}
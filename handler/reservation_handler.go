@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/config"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/notification"
+	"dgw-technical-test/repository"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReservationHandler struct {
+	ReservationRepository repository.ReservationRepository
+	BookRepository        repository.BookRepository
+	ReservationPolicy     *config.ReservationPolicy
+	Notifier              notification.Notifier
+	Validate              *validator.Validate
+}
+
+func NewReservationHandler(reservationRepository repository.ReservationRepository, bookRepository repository.BookRepository, reservationPolicy *config.ReservationPolicy, notifier notification.Notifier, validate *validator.Validate) *ReservationHandler {
+	return &ReservationHandler{
+		ReservationRepository: reservationRepository,
+		BookRepository:        bookRepository,
+		ReservationPolicy:     reservationPolicy,
+		Notifier:              notifier,
+		Validate:              validate,
+	}
+}
+
+// @Summary      Reserve book
+// @Description  Join the waitlist of a book that is out of stock
+// @Tags         Reservations
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.ReservationCreateRequest  true  "Reservation Request"
+// @Success      201      {object}  dto.ReservationResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /reservations [post]
+// @Security     Bearer
+func (handler *ReservationHandler) Create(c *fiber.Ctx) error {
+	requestBody := new(dto.ReservationCreateRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	book, err := handler.BookRepository.FindById(requestBody.BookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "book not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if book.Stock > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "book is in stock and can be rented directly"})
+	}
+
+	reservation := &entity.Reservation{
+		UserID: claims.UserID,
+		BookID: book.ID,
+	}
+
+	if err := handler.ReservationRepository.Create(reservation); err != nil {
+		if errors.Is(err, repository.ErrAlreadyReserved) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "book is already reserved"})
+		}
+		if errors.Is(err, repository.ErrAccountErased) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "account has been erased"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Successfully reserved book",
+		"data":    newReservationResponse(reservation),
+	})
+}
+
+// @Summary      Get my reservations
+// @Description  Retrieves the waiting and held reservations of the logged in user
+// @Tags         Reservations
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   dto.ReservationResponse
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /reservations/me [get]
+// @Security     Bearer
+func (handler *ReservationHandler) FindMine(c *fiber.Ctx) error {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	reservations, err := handler.ReservationRepository.FindActiveByUserId(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newReservationResponses(reservations))
+}
+
+// @Summary      Cancel reservation
+// @Description  Leave the waitlist or give up a held copy
+// @Tags         Reservations
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /reservations/:id [delete]
+// @Security     Bearer
+func (handler *ReservationHandler) Cancel(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	reservationId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid reservation id"})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	reservation, err := handler.ReservationRepository.FindById(reservationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "reservation not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if reservation.UserID != claims.UserID && !claims.HasPermission(entity.PermissionReservationsManage) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "reservation not found"})
+	}
+
+	next, err := handler.ReservationRepository.Cancel(reservation, time.Now().Add(handler.ReservationPolicy.HoldDuration))
+	if err != nil {
+		if errors.Is(err, repository.ErrReservationClosed) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "reservation is no longer active"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if next != nil {
+		if err := handler.Notifier.ReservationHeld(*next); err != nil {
+			log.Printf("failed to notify reservation %d: %v\n", next.ID, err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully cancelled reservation"})
+}
+
+// @Summary      Get book queue
+// @Description  Retrieves the held and waiting reservations of a book in queue order
+// @Tags         Reservations
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   dto.ReservationResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /reservations/books/:id [get]
+// @Security     Bearer
+func (handler *ReservationHandler) FindQueue(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	bookId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid book id"})
+	}
+
+	reservations, err := handler.ReservationRepository.FindQueueByBookId(bookId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newReservationResponses(reservations))
+}
+
+// @Summary      Move reservation
+// @Description  Move a waiting reservation to another position in its book queue
+// @Tags         Reservations
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.ReservationMoveRequest  true  "Move Request"
+// @Success      200      {object}  dto.ReservationResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /reservations/:id/position [put]
+// @Security     Bearer
+func (handler *ReservationHandler) Move(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	reservationId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid reservation id"})
+	}
+
+	requestBody := new(dto.ReservationMoveRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	reservation, err := handler.ReservationRepository.FindById(reservationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "reservation not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.ReservationRepository.Move(reservation, requestBody.Position); err != nil {
+		if errors.Is(err, repository.ErrReservationNotInQueue) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "only waiting reservations can be moved"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully moved reservation",
+		"data":    newReservationResponse(reservation),
+	})
+}
+
+func newReservationResponse(reservation *entity.Reservation) dto.ReservationResponse {
+	return dto.ReservationResponse{
+		ID:            reservation.ID,
+		UserID:        reservation.UserID,
+		BookID:        reservation.BookID,
+		Position:      reservation.Position,
+		Status:        reservation.Status,
+		HoldExpiresAt: reservation.HoldExpiresAt,
+		CreatedAt:     reservation.CreatedAt,
+	}
+}
+
+func newReservationResponses(reservations []entity.Reservation) []dto.ReservationResponse {
+	responses := make([]dto.ReservationResponse, 0, len(reservations))
+	for i := range reservations {
+		responses = append(responses, newReservationResponse(&reservations[i]))
+	}
+
+	return responses
+}
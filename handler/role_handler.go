@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/repository"
+	"errors"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RoleHandler struct {
+	RoleRepository repository.RoleRepository
+	Validate       *validator.Validate
+}
+
+func NewRoleHandler(roleRepository repository.RoleRepository, validate *validator.Validate) *RoleHandler {
+	return &RoleHandler{
+		RoleRepository: roleRepository,
+		Validate:       validate,
+	}
+}
+
+// @Summary      Create role
+// @Description  Add a new role without permissions
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.RoleCreateRequest  true  "Create Request"
+// @Success      201      {object}  dto.RoleResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /roles [post]
+// @Security     Bearer
+func (handler *RoleHandler) Create(c *fiber.Ctx) error {
+	requestBody := new(dto.RoleCreateRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	role := &entity.Role{
+		Name:        requestBody.Name,
+		Description: requestBody.Description,
+	}
+
+	if err := handler.RoleRepository.Create(role); err != nil {
+		if errors.Is(err, repository.ErrRoleExists) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "role already exists"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := dto.RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: []string{},
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Successfully added new role",
+		"data":    responseBody,
+	})
+}
+
+// @Summary      Get all roles
+// @Description  Retrieves every role with its permissions
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   dto.RoleResponse
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /roles [get]
+// @Security     Bearer
+func (handler *RoleHandler) FindAll(c *fiber.Ctx) error {
+	roles, err := handler.RoleRepository.FindAll()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := make([]dto.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		permissions, err := handler.RoleRepository.FindPermissionNames(role.Name)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		responseBody = append(responseBody, dto.RoleResponse{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: permissions,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(responseBody)
+}
+
+// @Summary      Grant permissions
+// @Description  Grant permissions to a role; users get them on their next login
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.RoleGrantRequest  true  "Grant Request"
+// @Success      200      {object}  dto.RoleResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /roles/:id/permissions [post]
+// @Security     Bearer
+func (handler *RoleHandler) GrantPermissions(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	roleId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid role id"})
+	}
+
+	requestBody := new(dto.RoleGrantRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	role, err := handler.RoleRepository.FindById(roleId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "role not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.RoleRepository.GrantPermissions(role.ID, requestBody.Permissions); err != nil {
+		if errors.Is(err, repository.ErrUnknownPermission) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown permission"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return handler.roleResponse(c, role, "Successfully granted permissions")
+}
+
+// @Summary      Revoke permission
+// @Description  Revoke a permission from a role; users lose it on their next login
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.RoleResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /roles/:id/permissions/:permission [delete]
+// @Security     Bearer
+func (handler *RoleHandler) RevokePermission(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	roleId, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid role id"})
+	}
+
+	role, err := handler.RoleRepository.FindById(roleId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "role not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.RoleRepository.RevokePermission(role.ID, c.Params("permission")); err != nil {
+		if errors.Is(err, repository.ErrUnknownPermission) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "role does not have this permission"})
+		}
+		if errors.Is(err, repository.ErrLastAdmin) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot revoke users:manage from the role of the last admins"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return handler.roleResponse(c, role, "Successfully revoked permission")
+}
+
+// @Summary      Get all permissions
+// @Description  Retrieves every permission that can be granted to a role
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   dto.PermissionResponse
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /permissions [get]
+// @Security     Bearer
+func (handler *RoleHandler) FindAllPermissions(c *fiber.Ctx) error {
+	permissions, err := handler.RoleRepository.FindAllPermissions()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := make([]dto.PermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		responseBody = append(responseBody, dto.PermissionResponse{
+			ID:          permission.ID,
+			Name:        permission.Name,
+			Description: permission.Description,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(responseBody)
+}
+
+func (handler *RoleHandler) roleResponse(c *fiber.Ctx, role *entity.Role, message string) error {
+	permissions, err := handler.RoleRepository.FindPermissionNames(role.Name)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": message,
+		"data": dto.RoleResponse{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: permissions,
+		},
+	})
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/repository"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	SessionRepository repository.SessionRepository
+}
+
+func NewSessionHandler(sessionRepository repository.SessionRepository) *SessionHandler {
+	return &SessionHandler{SessionRepository: sessionRepository}
+}
+
+// @Summary      Get my sessions
+// @Description  Lists the active sessions of the logged in user, most recently used first
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {array}   dto.SessionResponse
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/sessions [get]
+// @Security     Bearer
+func (handler *SessionHandler) FindMine(c *fiber.Ctx) error {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	sessions, err := handler.SessionRepository.FindActiveByUserId(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responseBody := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responseBody = append(responseBody, newSessionResponse(&session, claims.SessionID))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(responseBody)
+}
+
+// @Summary      Revoke session
+// @Description  Signs the logged in user out of one of their sessions
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/sessions/:id [delete]
+// @Security     Bearer
+func (handler *SessionHandler) Revoke(c *fiber.Ctx) error {
+	sessionId := c.Params("id")
+	if _, err := uuid.Parse(sessionId); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid session id"})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	session, err := handler.SessionRepository.FindById(sessionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "session not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if session.UserID != claims.UserID || session.RevokedAt != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "session not found"})
+	}
+
+	if err := handler.SessionRepository.Revoke(session.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully revoked session"})
+}
+
+// @Summary      Sign out everywhere
+// @Description  Revokes every session of the logged in user, including the current one
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/sessions/revoke-all [post]
+// @Security     Bearer
+func (handler *SessionHandler) RevokeAll(c *fiber.Ctx) error {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	if err := handler.SessionRepository.RevokeAllByUserId(claims.UserID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully signed out of every session"})
+}
+
+func newSessionResponse(session *entity.Session, currentSessionId string) dto.SessionResponse {
+	return dto.SessionResponse{
+		ID:         session.ID,
+		Device:     session.Device,
+		IPAddress:  session.IPAddress,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		RevokedAt:  session.RevokedAt,
+		Current:    session.ID == currentSessionId,
+	}
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"crypto/rand"
+	"dgw-technical-test/auth"
+	"dgw-technical-test/config"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/password"
+	"dgw-technical-test/repository"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorHandler struct {
+	UserRepository      repository.UserRepository
+	TwoFactorRepository repository.TwoFactorRepository
+	SessionRepository   repository.SessionRepository
+	TwoFactorPolicy     *config.TwoFactorPolicy
+	PasswordHasher      password.Hasher
+	Validate            *validator.Validate
+}
+
+func NewTwoFactorHandler(userRepository repository.UserRepository, twoFactorRepository repository.TwoFactorRepository, sessionRepository repository.SessionRepository, twoFactorPolicy *config.TwoFactorPolicy, passwordHasher password.Hasher, validate *validator.Validate) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		UserRepository:      userRepository,
+		TwoFactorRepository: twoFactorRepository,
+		SessionRepository:   sessionRepository,
+		TwoFactorPolicy:     twoFactorPolicy,
+		PasswordHasher:      passwordHasher,
+		Validate:            validate,
+	}
+}
+
+// @Summary      Enroll two-factor authentication
+// @Description  Generates a TOTP secret for the logged in user. It takes effect once confirmed with a code from the authenticator app.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.TwoFactorEnrollResponse
+// @Failure      401      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/2fa/enroll [post]
+// @Security     Bearer
+func (handler *TwoFactorHandler) Enroll(c *fiber.Ctx) error {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	user, err := handler.UserRepository.FindById(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.TwoFactorRepository.SetPendingSecret(user.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "two-factor authentication is already enabled"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(handler.TwoFactorPolicy.Issuer, user.Username, secret),
+	})
+}
+
+// @Summary      Confirm two-factor authentication
+// @Description  Enables two-factor authentication with a first code from the authenticator app and returns recovery codes. The recovery codes are only shown once.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.TwoFactorConfirmRequest  true  "Confirm Request"
+// @Success      200      {object}  dto.TwoFactorConfirmResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/2fa/confirm [post]
+// @Security     Bearer
+func (handler *TwoFactorHandler) Confirm(c *fiber.Ctx) error {
+	requestBody := new(dto.TwoFactorConfirmRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	user, err := handler.UserRepository.FindById(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "two-factor authentication is already enabled"})
+	}
+
+	if user.TOTPSecret == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "two-factor authentication has not been enrolled"})
+	}
+
+	step, valid := auth.ValidateTOTP(*user.TOTPSecret, requestBody.Code, time.Now())
+	if !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid two-factor code"})
+	}
+
+	if _, err := handler.TwoFactorRepository.UseStep(user.ID, step); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	recoveryCodes, err := generateRecoveryCodes(handler.TwoFactorPolicy.RecoveryCodeCount)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	recoveryCodeHashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		recoveryCodeHashes = append(recoveryCodeHashes, hashRecoveryCode(code))
+	}
+
+	if err := handler.TwoFactorRepository.Enable(user.ID, recoveryCodeHashes); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "two-factor authentication is already enabled"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The code just proved the second factor for the current session.
+	if err := handler.SessionRepository.MarkTwoFactorVerified(claims.SessionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully enabled two-factor authentication",
+		"data":    dto.TwoFactorConfirmResponse{RecoveryCodes: recoveryCodes},
+	})
+}
+
+// @Summary      Disable two-factor authentication
+// @Description  Turns off two-factor authentication after checking the password and a current or recovery code. Not allowed for roles that require it.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.TwoFactorDisableRequest  true  "Disable Request"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/2fa/disable [post]
+// @Security     Bearer
+func (handler *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	requestBody := new(dto.TwoFactorDisableRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve claims from token"})
+	}
+
+	user, err := handler.UserRepository.FindById(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if user.TOTPEnabledAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "two-factor authentication is not enabled"})
+	}
+
+	if handler.TwoFactorPolicy.RequiredFor(user.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "two-factor authentication is required for role " + user.Role})
+	}
+
+	if valid, err := handler.PasswordHasher.Verify(requestBody.Password, user.Password); err != nil || !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	valid, err := verifyTwoFactorCode(handler.TwoFactorRepository, user, requestBody.Code)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid two-factor code"})
+	}
+
+	if err := handler.TwoFactorRepository.Disable(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully disabled two-factor authentication"})
+}
+
+// verifyTwoFactorCode accepts either a code from the authenticator app that
+// was not used before or an unused recovery code, which is then spent.
+func verifyTwoFactorCode(twoFactorRepository repository.TwoFactorRepository, user *entity.User, code string) (bool, error) {
+	if user.TOTPSecret == nil || user.TOTPEnabledAt == nil {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if step, valid := auth.ValidateTOTP(*user.TOTPSecret, code, time.Now()); valid {
+		return twoFactorRepository.UseStep(user.ID, step)
+	}
+
+	return twoFactorRepository.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+}
+
+// generateRecoveryCodes returns codes like "k3q7m-x2p4d" with 50 bits of
+// entropy each.
+func generateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	for range count {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, so codes can be typed
+// the way they read.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return hashToken(normalized)
+}
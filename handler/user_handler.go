@@ -2,43 +2,90 @@ package handler
 
 import (
 	"database/sql"
+	"dgw-technical-test/auth"
+	"dgw-technical-test/config"
 	"dgw-technical-test/dto"
 	"dgw-technical-test/entity"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/middleware"
+	"dgw-technical-test/password"
 	"dgw-technical-test/repository"
 	"errors"
-	"os"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	UserRepository repository.UserRepository
-	Validate       *validator.Validate
+	UserRepository              repository.UserRepository
+	RoleRepository              repository.RoleRepository
+	RefreshTokenRepository      repository.RefreshTokenRepository
+	SessionRepository           repository.SessionRepository
+	LoginAttemptRepository      repository.LoginAttemptRepository
+	EmailVerificationRepository repository.EmailVerificationRepository
+	TwoFactorRepository         repository.TwoFactorRepository
+	AuthConfig                  *config.AuthConfig
+	LoginPolicy                 *config.LoginPolicy
+	TwoFactorPolicy             *config.TwoFactorPolicy
+	KeyManager                  *auth.KeyManager
+	PasswordChecker             *password.Checker
+	PasswordHasher              password.Hasher
+	Mailer                      mailer.Mailer
+	Validate                    *validator.Validate
+
+	// dummyPasswordHash is verified against when the username does not
+	// exist, so unknown and known usernames take the same time to reject.
+	dummyPasswordHash string
 }
 
-func NewUserHandler(userRepository repository.UserRepository, validate *validator.Validate) *UserHandler {
-	return &UserHandler{
-		UserRepository: userRepository,
-		Validate:       validate,
+func NewUserHandler(userRepository repository.UserRepository, roleRepository repository.RoleRepository, refreshTokenRepository repository.RefreshTokenRepository, sessionRepository repository.SessionRepository, loginAttemptRepository repository.LoginAttemptRepository, emailVerificationRepository repository.EmailVerificationRepository, twoFactorRepository repository.TwoFactorRepository, authConfig *config.AuthConfig, loginPolicy *config.LoginPolicy, twoFactorPolicy *config.TwoFactorPolicy, keyManager *auth.KeyManager, passwordChecker *password.Checker, passwordHasher password.Hasher, mailer mailer.Mailer, validate *validator.Validate) (*UserHandler, error) {
+	dummyPasswordHash, err := passwordHasher.Hash("dummy password")
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash dummy password: %w", err)
 	}
+
+	return &UserHandler{
+		UserRepository:              userRepository,
+		RoleRepository:              roleRepository,
+		RefreshTokenRepository:      refreshTokenRepository,
+		SessionRepository:           sessionRepository,
+		LoginAttemptRepository:      loginAttemptRepository,
+		EmailVerificationRepository: emailVerificationRepository,
+		TwoFactorRepository:         twoFactorRepository,
+		AuthConfig:                  authConfig,
+		LoginPolicy:                 loginPolicy,
+		TwoFactorPolicy:             twoFactorPolicy,
+		KeyManager:                  keyManager,
+		PasswordChecker:             passwordChecker,
+		PasswordHasher:              passwordHasher,
+		Mailer:                      mailer,
+		Validate:                    validate,
+		dummyPasswordHash:           dummyPasswordHash,
+	}, nil
 }
 
 // @Summary      Register a new user
-// @Description  Creates a new user account with the provided details.
+// @Description  Creates a new customer account with the provided details and emails a link to verify its address. Renting and reserving books requires a verified email.
 // @Tags         Users
 // @Accept       json
 // @Produce      json
 // @Param        request  body      dto.UserRegisterRequest  true  "Register Request"
 // @Success      201      {object}  dto.UserRegisterResponse
 // @Failure      400      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
 // @Failure      409      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /users/register [post]
 func (handler *UserHandler) Register(c *fiber.Ctx) error {
+	if !handler.AuthConfig.LocalLoginEnabled {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "password login is disabled, sign in through single sign-on"})
+	}
+
 	requestBody := new(dto.UserRegisterRequest)
 
 	if err := c.BodyParser(requestBody); err != nil {
@@ -49,29 +96,25 @@ func (handler *UserHandler) Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	existingUser, err := handler.UserRepository.FindUserByUsername(requestBody.Username)
-	if err == nil && existingUser != nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "username or email already exists"})
-	}
-
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+	taken, err := usernameOrEmailTaken(handler.UserRepository, requestBody.Username, requestBody.Email)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	existingUser, err = handler.UserRepository.FindUserByEmail(requestBody.Email)
-	if err == nil && existingUser != nil {
+	if taken {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "username or email already exists"})
 	}
 
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+	violations, err := handler.PasswordChecker.Check(requestBody.Password, requestBody.Username, requestBody.Email)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	if requestBody.Role != "Admin" && requestBody.Role != "Customer" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid role"})
+	if len(violations) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(weakPasswordResponse(violations))
 	}
 
-	hashPassword, err := bcrypt.GenerateFromPassword([]byte(requestBody.Password), bcrypt.DefaultCost)
+	hashPassword, err := handler.PasswordHasher.Hash(requestBody.Password)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -79,14 +122,19 @@ func (handler *UserHandler) Register(c *fiber.Ctx) error {
 	user := &entity.User{
 		Username: requestBody.Username,
 		Email:    requestBody.Email,
-		Password: string(hashPassword),
-		Role:     requestBody.Role,
+		Password: hashPassword,
+		Role:     entity.RoleCustomer,
 	}
 
 	if err := handler.UserRepository.Register(user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	// The account exists either way; the user can ask for a new email.
+	if err := sendVerificationEmail(handler.EmailVerificationRepository, handler.Mailer, handler.AuthConfig, user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v\n", user.ID, err)
+	}
+
 	responseBody := dto.UserRegisterResponse{
 		ID:       user.ID,
 		Username: user.Username,
@@ -101,18 +149,23 @@ func (handler *UserHandler) Register(c *fiber.Ctx) error {
 }
 
 // @Summary      User login
-// @Description  Authenticates a user and returns a JWT token.
+// @Description  Authenticates a user and returns a short-lived JWT access token and a refresh token. Users with two-factor authentication get a challenge token to complete at /users/login/2fa instead. Repeated failures lock the account and the client IP out for a growing period.
 // @Tags         Users
 // @Accept       json
 // @Produce      json
 // @Param        request  body      dto.UserLoginRequest  true  "Login Request"
-// @Success      200      {object}  map[string]string
+// @Success      200      {object}  map[string]interface{}
 // @Failure      400      {object}  map[string]string
 // @Failure      401      {object}  map[string]string
-// @Failure      404      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /users/login [post]
 func (uh *UserHandler) Login(c *fiber.Ctx) error {
+	if !uh.AuthConfig.LocalLoginEnabled {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "password login is disabled, sign in through single sign-on"})
+	}
+
 	requestBody := new(dto.UserLoginRequest)
 
 	if err := c.BodyParser(requestBody); err != nil {
@@ -123,31 +176,439 @@ func (uh *UserHandler) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	accountKey := accountAttemptKey(requestBody.Username)
+	ipKey := ipAttemptKey(c.IP())
+
+	lockedUntil, err := uh.LoginAttemptRepository.FindLockedUntil([]string{accountKey, ipKey}, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if lockedUntil != nil {
+		return tooManyLoginAttempts(c, *lockedUntil)
+	}
+
 	user, err := uh.UserRepository.FindUserByUsername(requestBody.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	passwordHash := uh.dummyPasswordHash
+	if user != nil {
+		passwordHash = user.Password
+	}
+
+	if valid, err := uh.PasswordHasher.Verify(requestBody.Password, passwordHash); err != nil || !valid || user == nil {
+		if err := uh.recordLoginFailure(accountKey, ipKey); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid username or password"})
+	}
+
+	if err := uh.LoginAttemptRepository.Clear(accountKey); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if uh.PasswordHasher.NeedsRehash(user.Password) {
+		uh.rehashPassword(user, requestBody.Password)
+	}
+
+	if reason := loginBlockedReason(user); reason != "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": reason})
+	}
+
+	if user.TOTPEnabledAt != nil {
+		responseBody, err := uh.startTwoFactorChallenge(user)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(responseBody)
+	}
+
+	tokens, err := uh.startSession(c, user, false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens["message"] = "Successfully login"
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// @Summary      Complete two-factor login
+// @Description  Exchanges the challenge token of a login and an authenticator or recovery code for the access token and refresh token.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.UserLoginTwoFactorRequest  true  "Two-Factor Request"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/login/2fa [post]
+func (uh *UserHandler) LoginTwoFactor(c *fiber.Ctx) error {
+	requestBody := new(dto.UserLoginTwoFactorRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := uh.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	invalidChallenge := fiber.Map{"error": "invalid or expired challenge, log in again"}
+
+	challenge, err := uh.TwoFactorRepository.FindChallengeByHash(hashToken(requestBody.ChallengeToken))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+			return c.Status(fiber.StatusUnauthorized).JSON(invalidChallenge)
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.Password)); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	if challenge.UsedAt != nil || challenge.Attempts >= uh.TwoFactorPolicy.MaxChallengeAttempts || time.Now().After(challenge.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(invalidChallenge)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	user, err := uh.UserRepository.FindById(challenge.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	accountKey := accountAttemptKey(user.Username)
+	ipKey := ipAttemptKey(c.IP())
+
+	lockedUntil, err := uh.LoginAttemptRepository.FindLockedUntil([]string{accountKey, ipKey}, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if lockedUntil != nil {
+		return tooManyLoginAttempts(c, *lockedUntil)
+	}
+
+	valid, err := verifyTwoFactorCode(uh.TwoFactorRepository, user, requestBody.Code)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if !valid {
+		if _, err := uh.TwoFactorRepository.RecordChallengeFailure(challenge.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if err := uh.recordLoginFailure(accountKey, ipKey); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid two-factor code"})
+	}
+
+	if reason := loginBlockedReason(user); reason != "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": reason})
+	}
+
+	if err := uh.TwoFactorRepository.CompleteChallenge(challenge.ID); err != nil {
+		if errors.Is(err, repository.ErrChallengeClosed) {
+			return c.Status(fiber.StatusUnauthorized).JSON(invalidChallenge)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens, err := uh.startSession(c, user, true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens["message"] = "Successfully login"
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// @Summary      Refresh tokens
+// @Description  Exchanges a refresh token for a new access token and refresh token. Replaying a refresh token that was already used revokes every session of its user.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.UserRefreshRequest  true  "Refresh Request"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/refresh [post]
+func (handler *UserHandler) Refresh(c *fiber.Ctx) error {
+	requestBody := new(dto.UserRefreshRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	refreshToken, err := handler.RefreshTokenRepository.FindByHash(hashToken(requestBody.RefreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid refresh token"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if refreshToken.RevokedAt != nil {
+		if refreshToken.ReplacedBy != nil {
+			return handler.rejectReusedToken(c, refreshToken)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "refresh token has been revoked"})
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "refresh token has expired"})
+	}
+
+	user, err := handler.UserRepository.FindById(refreshToken.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	accessToken, err := handler.signAccessToken(user, refreshToken.FamilyID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	nextRefreshToken, nextToken, err := handler.newRefreshToken(user.ID, refreshToken.FamilyID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if err := handler.RefreshTokenRepository.Rotate(refreshToken, nextToken); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+			return handler.rejectReusedToken(c, refreshToken)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.SessionRepository.Touch(refreshToken.FamilyID, time.Now()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Successfully login",
-		"token":   tokenString,
+		"message":       "Successfully refreshed token",
+		"token":         accessToken,
+		"refresh_token": nextRefreshToken,
+		"expires_in":    int(handler.AuthConfig.AccessTokenTTL.Seconds()),
+	})
+}
+
+// @Summary      User logout
+// @Description  Revokes the session of the refresh token and every token rotated from the same login.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.UserLogoutRequest  true  "Logout Request"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/logout [post]
+func (handler *UserHandler) Logout(c *fiber.Ctx) error {
+	requestBody := new(dto.UserLogoutRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	refreshToken, err := handler.RefreshTokenRepository.FindByHash(hashToken(requestBody.RefreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid refresh token"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.SessionRepository.Revoke(refreshToken.FamilyID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Successfully logout"})
+}
+
+// rejectReusedToken handles a refresh token that is presented again after it
+// was rotated or revoked. The token may have been stolen, so every session of
+// its user is revoked.
+func (handler *UserHandler) rejectReusedToken(c *fiber.Ctx, refreshToken *entity.RefreshToken) error {
+	if err := handler.SessionRepository.RevokeAllByUserId(refreshToken.UserID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "refresh token reuse detected, all sessions have been revoked"})
+}
+
+// rehashPassword upgrades the stored hash of a password that was just
+// verified to the current algorithm and parameters. Failures only delay the
+// upgrade to a later login.
+func (handler *UserHandler) rehashPassword(user *entity.User, password string) {
+	hashPassword, err := handler.PasswordHasher.Hash(password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %d: %v\n", user.ID, err)
+		return
+	}
+
+	if err := handler.UserRepository.UpdatePassword(user.ID, hashPassword); err != nil {
+		log.Printf("failed to rehash password of user %d: %v\n", user.ID, err)
+		return
+	}
+
+	user.Password = hashPassword
+}
+
+// startTwoFactorChallenge creates the challenge a user with two-factor
+// authentication completes at /users/login/2fa and returns the response
+// that hands it out.
+func (handler *UserHandler) startTwoFactorChallenge(user *entity.User) (fiber.Map, error) {
+	challengeToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &entity.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(challengeToken),
+		ExpiresAt: time.Now().Add(handler.TwoFactorPolicy.ChallengeTTL),
+	}
+
+	if err := handler.TwoFactorRepository.CreateChallenge(challenge); err != nil {
+		return nil, err
+	}
+
+	return fiber.Map{
+		"message":             "Two-factor authentication required",
+		"two_factor_required": true,
+		"challenge_token":     challengeToken,
+		"expires_in":          int(handler.TwoFactorPolicy.ChallengeTTL.Seconds()),
+	}, nil
+}
+
+// startSession records a new session for the client of the request and
+// issues its first tokens.
+func (handler *UserHandler) startSession(c *fiber.Ctx, user *entity.User, twoFactorVerified bool) (fiber.Map, error) {
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	session := &entity.Session{
+		ID:                uuid.NewString(),
+		UserID:            user.ID,
+		Device:            describeDevice(c.Get("X-Device-Name"), userAgent),
+		IPAddress:         c.IP(),
+		UserAgent:         userAgent,
+		TwoFactorVerified: twoFactorVerified,
+	}
+
+	if err := handler.SessionRepository.Create(session); err != nil {
+		return nil, err
+	}
+
+	return handler.issueTokens(user, session.ID)
+}
+
+func (handler *UserHandler) recordLoginFailure(accountKey string, ipKey string) error {
+	if err := recordFailedLogin(handler.LoginAttemptRepository, handler.LoginPolicy, accountKey, handler.LoginPolicy.AccountMaxFailures); err != nil {
+		return err
+	}
+
+	return recordFailedLogin(handler.LoginAttemptRepository, handler.LoginPolicy, ipKey, handler.LoginPolicy.IPMaxFailures)
+}
+
+// issueTokens signs an access token for the user and stores the first
+// refresh token of the session. The result is ready to be sent as a response.
+func (handler *UserHandler) issueTokens(user *entity.User, sessionId string) (fiber.Map, error) {
+	accessToken, err := handler.signAccessToken(user, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, token, err := handler.newRefreshToken(user.ID, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handler.RefreshTokenRepository.Create(token); err != nil {
+		return nil, err
+	}
+
+	return fiber.Map{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(handler.AuthConfig.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (handler *UserHandler) signAccessToken(user *entity.User, sessionId string) (string, error) {
+	permissions, err := handler.RoleRepository.FindPermissionNames(user.Role)
+	if err != nil {
+		return "", err
+	}
+
+	return handler.KeyManager.Sign(middleware.Claims{
+		UserID:      user.ID,
+		Role:        user.Role,
+		Permissions: permissions,
+		SessionID:   sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(handler.AuthConfig.AccessTokenTTL)),
+		},
 	})
 }
+
+// newRefreshToken generates a random refresh token. Only its hash is kept in
+// the returned entity; the plain token is returned once for the client.
+func (handler *UserHandler) newRefreshToken(userId int, familyId string) (string, *entity.RefreshToken, error) {
+	refreshToken, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := &entity.RefreshToken{
+		UserID:    userId,
+		FamilyID:  familyId,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(handler.AuthConfig.RefreshTokenTTL),
+	}
+
+	return refreshToken, token, nil
+}
+
+func usernameOrEmailTaken(userRepository repository.UserRepository, username string, email string) (bool, error) {
+	if _, err := userRepository.FindUserByUsername(username); err == nil {
+		return true, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	if _, err := userRepository.FindUserByEmail(email); err == nil {
+		return true, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// loginBlockedReason explains why a user who proved their identity may still
+// not log in, or returns an empty string when they may.
+func loginBlockedReason(user *entity.User) string {
+	if user.SuspendedAt != nil {
+		return "account is suspended"
+	}
+
+	if user.PasswordResetRequired {
+		return "password reset required, check your email for a reset link"
+	}
+
+	return ""
+}
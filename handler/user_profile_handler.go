@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"database/sql"
+	"dgw-technical-test/dto"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/middleware"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      Get my profile
+// @Description  Retrieves the account of the logged in user
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Success      200      {object}  dto.UserProfileResponse
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/me [get]
+// @Security     Bearer
+func (handler *UserHandler) FindMe(c *fiber.Ctx) error {
+	user, err := handler.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newUserProfileResponse(user))
+}
+
+// @Summary      Update my profile
+// @Description  Changes the username of the logged in user
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.UserProfileUpdateRequest  true  "Profile Request"
+// @Success      200      {object}  dto.UserProfileResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/me [patch]
+// @Security     Bearer
+func (handler *UserHandler) UpdateMe(c *fiber.Ctx) error {
+	requestBody := new(dto.UserProfileUpdateRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user, err := handler.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if requestBody.Username != user.Username {
+		if _, err := handler.UserRepository.FindUserByUsername(requestBody.Username); err == nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "username already exists"})
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		user.Username = requestBody.Username
+		if err := handler.UserRepository.Update(user); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully updated profile",
+		"data":    newUserProfileResponse(user),
+	})
+}
+
+// @Summary      Change my email
+// @Description  Changes the email of the logged in user after checking the current password. The new address has to be verified again; the old address is told about the change.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.UserEmailChangeRequest  true  "Email Request"
+// @Success      200      {object}  dto.UserProfileResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/me/email [put]
+// @Security     Bearer
+func (handler *UserHandler) ChangeEmail(c *fiber.Ctx) error {
+	requestBody := new(dto.UserEmailChangeRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user, err := handler.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	valid, lockedUntil, err := handler.checkCurrentPassword(c, user, requestBody.CurrentPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if lockedUntil != nil {
+		return tooManyLoginAttempts(c, *lockedUntil)
+	}
+
+	if !valid {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "current password is incorrect"})
+	}
+
+	if requestBody.Email == user.Email {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email is unchanged"})
+	}
+
+	if _, err := handler.UserRepository.FindUserByEmail(requestBody.Email); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "email already exists"})
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	previousEmail := user.Email
+	user.Email = requestBody.Email
+	user.EmailVerifiedAt = nil
+
+	if err := handler.UserRepository.Update(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := sendVerificationEmail(handler.EmailVerificationRepository, handler.Mailer, handler.AuthConfig, user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v\n", user.ID, err)
+	}
+
+	notice := mailer.Message{
+		To:      previousEmail,
+		Subject: "Your email address was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s. If you did not make this change, reset your password right away.\n", user.Username, user.Email),
+	}
+
+	if err := handler.Mailer.Send(notice); err != nil {
+		log.Printf("failed to send email change notice to user %d: %v\n", user.ID, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully changed email, check the new address to verify it",
+		"data":    newUserProfileResponse(user),
+	})
+}
+
+// @Summary      Change my password
+// @Description  Changes the password of the logged in user after checking the current one. Every session is signed out and new tokens are returned for the current client.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param Authorization header string true "With the bearer started"
+// @Param        request  body      dto.UserPasswordChangeRequest  true  "Password Request"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /users/me/password [put]
+// @Security     Bearer
+func (handler *UserHandler) ChangePassword(c *fiber.Ctx) error {
+	requestBody := new(dto.UserPasswordChangeRequest)
+
+	if err := c.BodyParser(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.Validate.Struct(requestBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	session, ok := middleware.GetSession(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to retrieve session from token"})
+	}
+
+	user, err := handler.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	valid, lockedUntil, err := handler.checkCurrentPassword(c, user, requestBody.CurrentPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if lockedUntil != nil {
+		return tooManyLoginAttempts(c, *lockedUntil)
+	}
+
+	if !valid {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "current password is incorrect"})
+	}
+
+	violations, err := handler.PasswordChecker.Check(requestBody.NewPassword, user.Username, user.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(violations) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(weakPasswordResponse(violations))
+	}
+
+	hashPassword, err := handler.PasswordHasher.Hash(requestBody.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := handler.UserRepository.ChangePassword(user.ID, hashPassword); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens, err := handler.startSession(c, user, session.TwoFactorVerified)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens["message"] = "Successfully changed password"
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+func (handler *UserHandler) currentUser(c *fiber.Ctx) (*entity.User, error) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return nil, errors.New("failed to retrieve claims from token")
+	}
+
+	return handler.UserRepository.FindById(claims.UserID)
+}
+
+// checkCurrentPassword confirms a sensitive change with the password of the
+// user. Wrong passwords count as failed logins, so a stolen access token
+// cannot be used to guess the password.
+func (handler *UserHandler) checkCurrentPassword(c *fiber.Ctx, user *entity.User, currentPassword string) (bool, *time.Time, error) {
+	accountKey := accountAttemptKey(user.Username)
+	ipKey := ipAttemptKey(c.IP())
+
+	lockedUntil, err := handler.LoginAttemptRepository.FindLockedUntil([]string{accountKey, ipKey}, time.Now())
+	if err != nil || lockedUntil != nil {
+		return false, lockedUntil, err
+	}
+
+	if valid, err := handler.PasswordHasher.Verify(currentPassword, user.Password); err != nil || !valid {
+		return false, nil, handler.recordLoginFailure(accountKey, ipKey)
+	}
+
+	return true, nil, nil
+}
+
+// signedInRecently reports whether a user linked to an identity provider
+// logged in to the session of the request within ReauthenticationMaxAge.
+// Such users never learn their local password, so a fresh single sign-on
+// login stands in for it.
+func (handler *UserHandler) signedInRecently(c *fiber.Ctx, user *entity.User) bool {
+	if user.OIDCSubject == nil {
+		return false
+	}
+
+	session, ok := middleware.GetSession(c)
+	return ok && time.Since(session.CreatedAt) <= handler.AuthConfig.ReauthenticationMaxAge
+}
+
+func newUserProfileResponse(user *entity.User) dto.UserProfileResponse {
+	return dto.UserProfileResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Role:             user.Role,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+		CreatedAt:        user.CreatedAt,
+	}
+}
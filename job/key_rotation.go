@@ -0,0 +1,37 @@
+package job
+
+import (
+	"dgw-technical-test/auth"
+	"log"
+	"time"
+)
+
+// RunKeyRotation rotates the token signing key once it is older than the
+// rotation interval and picks up keys rotated by other instances, until stop
+// is closed.
+func RunKeyRotation(keyManager *auth.KeyManager, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rotated, err := keyManager.RotateIfDue()
+			if err != nil {
+				log.Printf("failed to rotate signing key: %v\n", err)
+				continue
+			}
+
+			if rotated {
+				log.Println("Rotated token signing key")
+				continue
+			}
+
+			if err := keyManager.Reload(); err != nil {
+				log.Printf("failed to reload signing keys: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package job
+
+import (
+	"dgw-technical-test/config"
+	"dgw-technical-test/notification"
+	"dgw-technical-test/repository"
+	"log"
+	"time"
+)
+
+// RunReservationExpiry periodically releases holds that were not picked up in
+// time, passing the copy to the next customer in the queue, until stop is
+// closed.
+func RunReservationExpiry(reservationRepository repository.ReservationRepository, reservationPolicy *config.ReservationPolicy, notifier notification.Notifier, stop <-chan struct{}) {
+	ticker := time.NewTicker(reservationPolicy.ExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			held, err := reservationRepository.ExpireHolds(now, now.Add(reservationPolicy.HoldDuration))
+			if err != nil {
+				log.Printf("failed to expire reservation holds: %v\n", err)
+				continue
+			}
+
+			for _, reservation := range held {
+				if err := notifier.ReservationHeld(reservation); err != nil {
+					log.Printf("failed to notify reservation %d: %v\n", reservation.ID, err)
+				}
+			}
+		}
+	}
+}
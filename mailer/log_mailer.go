@@ -0,0 +1,16 @@
+package mailer
+
+import "log"
+
+// LogMailer prints messages instead of sending them. It is meant for local
+// development.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (mailer *LogMailer) Send(message Message) error {
+	log.Printf("mail to %s: %s\n%s\n", message.To, message.Subject, message.Body)
+	return nil
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"bytes"
+	"dgw-technical-test/config"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(message Message) error
+}
+
+// NewMailer returns the mailer selected by MAIL_DRIVER.
+func NewMailer(mailConfig *config.MailConfig) Mailer {
+	switch mailConfig.Driver {
+	case "smtp":
+		return NewSMTPMailer(mailConfig.SMTPHost, mailConfig.SMTPPort, mailConfig.SMTPUsername, mailConfig.SMTPPassword, mailConfig.From)
+	case "file":
+		return NewFileMailer(mailConfig.FileDir, mailConfig.From)
+	default:
+		return NewLogMailer()
+	}
+}
+
+// format renders the message as a plain text RFC 5322 email.
+func format(from string, message Message) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&buf, "To: %s\r\n", headerValue(message.To))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", headerValue(message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return buf.Bytes()
+}
+
+// headerValue drops line breaks so a value cannot inject extra headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
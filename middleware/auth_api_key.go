@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/repository"
+	"encoding/hex"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CustomApiKeyMiddleware authenticates requests carrying an X-API-Key header
+// and hands every other request to next, usually CustomJwtMiddleware. The
+// claims it stores grant the scopes of the key that the role of its owner
+// still has, so demoting the owner also narrows their keys. Every route that
+// accepts API keys must check a permission with RequirePermission, otherwise
+// a key would not be limited to its scopes.
+func CustomApiKeyMiddleware(apiKeyRepository repository.ApiKeyRepository, userRepository repository.UserRepository, roleRepository repository.RoleRepository, next fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("X-API-Key")
+		if key == "" {
+			return next(c)
+		}
+
+		invalidKey := fiber.Map{"error": "Invalid or expired API key"}
+
+		if !strings.HasPrefix(key, entity.ApiKeyPrefix) {
+			return c.Status(fiber.StatusUnauthorized).JSON(invalidKey)
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		apiKey, err := apiKeyRepository.FindByHash(hex.EncodeToString(sum[:]))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		now := time.Now()
+		if apiKey == nil || apiKey.RevokedAt != nil || !now.Before(apiKey.ExpiresAt) {
+			return c.Status(fiber.StatusUnauthorized).JSON(invalidKey)
+		}
+
+		user, err := userRepository.FindById(apiKey.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if user.SuspendedAt != nil || user.PasswordResetRequired {
+			return c.Status(fiber.StatusUnauthorized).JSON(invalidKey)
+		}
+
+		rolePermissions, err := roleRepository.FindPermissionNames(user.Role)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		permissions := []string{}
+		for _, scope := range apiKey.Scopes {
+			if slices.Contains(rolePermissions, scope) {
+				permissions = append(permissions, scope)
+			}
+		}
+
+		if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastSeenResolution {
+			if err := apiKeyRepository.Touch(apiKey.ID, now, c.IP()); err != nil {
+				log.Printf("failed to update last use of API key %d: %v\n", apiKey.ID, err)
+			}
+		}
+
+		c.Locals("user", &Claims{
+			UserID:      user.ID,
+			Role:        user.Role,
+			Permissions: permissions,
+		})
+		c.Locals("api_key", apiKey)
+
+		return c.Next()
+	}
+}
@@ -1,14 +1,23 @@
 package middleware
 
 import (
-	"os"
+	"database/sql"
+	"dgw-technical-test/auth"
+	"dgw-technical-test/repository"
+	"errors"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func CustomJwtMiddleware() fiber.Handler {
+// lastSeenResolution limits how often a busy session writes its last seen
+// time.
+const lastSeenResolution = time.Minute
+
+func CustomJwtMiddleware(keyManager *auth.KeyManager, sessionRepository repository.SessionRepository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -20,21 +29,32 @@ func CustomJwtMiddleware() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid Authorization header format"})
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unexpected signing method"})
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		claims := new(Claims)
+		token, err := jwt.ParseWithClaims(tokenString, claims, keyManager.Keyfunc,
+			jwt.WithValidMethods(keyManager.ValidMethods()), jwt.WithExpirationRequired())
 
-		if err != nil || !token.Valid {
+		if err != nil || !token.Valid || claims.SessionID == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Locals("user", claims)
+		session, err := sessionRepository.FindById(claims.SessionID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if session == nil || session.RevokedAt != nil || session.UserID != claims.UserID {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session has been revoked"})
 		}
 
+		if now := time.Now(); now.Sub(session.LastSeenAt) >= lastSeenResolution {
+			if err := sessionRepository.Touch(session.ID, now); err != nil {
+				log.Printf("failed to update last seen of session %s: %v\n", session.ID, err)
+			}
+		}
+
+		c.Locals("user", claims)
+		c.Locals("session", session)
+
 		return c.Next()
 	}
 }
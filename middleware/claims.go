@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"dgw-technical-test/entity"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the payload of the access tokens issued at login.
+type Claims struct {
+	UserID      int      `json:"user_id"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	SessionID   string   `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+func (claims *Claims) HasPermission(permission string) bool {
+	return slices.Contains(claims.Permissions, permission)
+}
+
+// GetClaims returns the claims CustomJwtMiddleware stored for the request.
+func GetClaims(c *fiber.Ctx) (*Claims, bool) {
+	claims, ok := c.Locals("user").(*Claims)
+	return claims, ok
+}
+
+// GetSession returns the session CustomJwtMiddleware loaded for the request.
+func GetSession(c *fiber.Ctx) (*entity.Session, bool) {
+	session, ok := c.Locals("session").(*entity.Session)
+	return session, ok
+}
+
+// GetApiKey returns the API key CustomApiKeyMiddleware authenticated the
+// request with.
+func GetApiKey(c *fiber.Ctx) (*entity.ApiKey, bool) {
+	apiKey, ok := c.Locals("api_key").(*entity.ApiKey)
+	return apiKey, ok
+}
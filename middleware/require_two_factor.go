@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"dgw-technical-test/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireTwoFactor rejects sessions that were not verified with a second
+// factor when the policy makes two-factor authentication mandatory for the
+// role of the user. API keys pass, since they can only be created from a
+// session that passed this check. It must run after CustomJwtMiddleware.
+func RequireTwoFactor(policy *config.TwoFactorPolicy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := GetClaims(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
+		}
+
+		if _, ok := GetApiKey(c); ok {
+			return c.Next()
+		}
+
+		session, ok := GetSession(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
+		}
+
+		if policy.RequiredFor(claims.Role) && !session.TwoFactorVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Two-factor authentication is required for role " + claims.Role})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"dgw-technical-test/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireVerifiedEmail only lets users who verified their email address
+// through. It reads the user instead of the token claims, so it takes effect
+// right after verification. It must run after CustomJwtMiddleware.
+func RequireVerifiedEmail(userRepository repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := GetClaims(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
+		}
+
+		user, err := userRepository.FindById(claims.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if user.EmailVerifiedAt == nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Email address is not verified"})
+		}
+
+		return c.Next()
+	}
+}
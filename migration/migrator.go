@@ -0,0 +1,251 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// advisoryLockKey serialises migrator runs across processes sharing the
+// database.
+const advisoryLockKey = 7_162_534
+
+var ErrChecksumMismatch = errors.New("applied migration does not match its file")
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+type Migrator struct {
+	DB         *sqlx.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Up applies every pending migration.
+func (migrator *Migrator) Up() error {
+	return migrator.To(migrator.latestVersion())
+}
+
+// Down rolls back the most recently applied migration.
+func (migrator *Migrator) Down() error {
+	return migrator.withLock(func(applied []appliedMigration) error {
+		if len(applied) == 0 {
+			return nil
+		}
+
+		last := applied[len(applied)-1]
+		return migrator.revert(migrator.find(last.Version))
+	})
+}
+
+// To applies or rolls back migrations until the schema is at version.
+func (migrator *Migrator) To(version int) error {
+	if version != 0 && migrator.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return migrator.withLock(func(applied []appliedMigration) error {
+		isApplied := make(map[int]bool, len(applied))
+		for _, migration := range applied {
+			isApplied[migration.Version] = true
+		}
+
+		for i := len(applied) - 1; i >= 0 && applied[i].Version > version; i-- {
+			if err := migrator.revert(migrator.find(applied[i].Version)); err != nil {
+				return err
+			}
+		}
+
+		for i := range migrator.Migrations {
+			migration := &migrator.Migrations[i]
+			if !isApplied[migration.Version] && migration.Version <= version {
+				if err := migrator.apply(migration); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status lists every known migration and when it was applied.
+func (migrator *Migrator) Status() ([]Status, error) {
+	if err := migrator.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := migrator.verify()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := make(map[int]time.Time, len(applied))
+	for _, migration := range applied {
+		appliedAt[migration.Version] = migration.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(migrator.Migrations))
+	for _, migration := range migrator.Migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Pending returns how many migrations have not been applied yet. It fails
+// when the applied migrations drifted from the embedded files.
+func (migrator *Migrator) Pending() (int, error) {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func (migrator *Migrator) withLock(run func(applied []appliedMigration) error) error {
+	if err := migrator.ensureTable(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := migrator.DB.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+
+	applied, err := migrator.verify()
+	if err != nil {
+		return err
+	}
+
+	return run(applied)
+}
+
+func (migrator *Migrator) ensureTable() error {
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR NOT NULL,
+		checksum VARCHAR NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	_, err := migrator.DB.Exec(query)
+	return err
+}
+
+// verify returns the applied migrations and makes sure each of them still
+// matches an embedded file with the same checksum.
+func (migrator *Migrator) verify() ([]appliedMigration, error) {
+	var applied []appliedMigration
+	if err := migrator.DB.Select(&applied, "SELECT * FROM schema_migrations ORDER BY version"); err != nil {
+		return nil, err
+	}
+
+	for _, migration := range applied {
+		known := migrator.find(migration.Version)
+		if known == nil {
+			return nil, fmt.Errorf("migration %d (%s) is applied but has no file", migration.Version, migration.Name)
+		}
+
+		if known.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, ErrChecksumMismatch)
+		}
+	}
+
+	return applied, nil
+}
+
+func (migrator *Migrator) apply(migration *Migration) error {
+	tx, err := migrator.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration.Up); err != nil {
+		return fmt.Errorf("migration %d (%s) up: %w", migration.Version, migration.Name, err)
+	}
+
+	query := "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)"
+	if _, err := tx.Exec(query, migration.Version, migration.Name, migration.Checksum); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (migrator *Migrator) revert(migration *Migration) error {
+	tx, err := migrator.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration.Down); err != nil {
+		return fmt.Errorf("migration %d (%s) down: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (migrator *Migrator) find(version int) *Migration {
+	for i := range migrator.Migrations {
+		if migrator.Migrations[i].Version == version {
+			return &migrator.Migrations[i]
+		}
+	}
+
+	return nil
+}
+
+func (migrator *Migrator) latestVersion() int {
+	if len(migrator.Migrations) == 0 {
+		return 0
+	}
+
+	return migrator.Migrations[len(migrator.Migrations)-1].Version
+}
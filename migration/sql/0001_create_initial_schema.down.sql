@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS Rents;
+DROP TABLE IF EXISTS Books;
+DROP TABLE IF EXISTS Users;
+DROP FUNCTION IF EXISTS update_modified_column();
@@ -1,4 +1,4 @@
-CREATE TABLE Users (
+CREATE TABLE IF NOT EXISTS Users (
 	id SERIAL PRIMARY KEY,
 	username VARCHAR NOT NULL,
 	email VARCHAR NOT NULL UNIQUE,
@@ -8,7 +8,7 @@ CREATE TABLE Users (
 	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE Books (
+CREATE TABLE IF NOT EXISTS Books (
 	id SERIAL PRIMARY KEY,
 	name VARCHAR NOT NULL,
 	genre VARCHAR NOT NULL,
@@ -20,7 +20,7 @@ CREATE TABLE Books (
 	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE Rents (
+CREATE TABLE IF NOT EXISTS Rents (
 	id SERIAL PRIMARY KEY,
 	user_id INT REFERENCES Users(id) NOT NULL,
 	book_id INT REFERENCES Books(id) NOT NULL,
@@ -37,13 +37,14 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+DROP TRIGGER IF EXISTS update_user_modtime ON Users;
 CREATE TRIGGER update_user_modtime
 BEFORE UPDATE ON Users
 FOR EACH ROW
 EXECUTE FUNCTION update_modified_column();
 
-
+DROP TRIGGER IF EXISTS update_book_modtime ON Books;
 CREATE TRIGGER update_book_modtime
 BEFORE UPDATE ON Books
 FOR EACH ROW
-EXECUTE FUNCTION update_modified_column();
\ No newline at end of file
+EXECUTE FUNCTION update_modified_column();
@@ -0,0 +1,3 @@
+ALTER TABLE Rents DROP COLUMN IF EXISTS renewal_count;
+ALTER TABLE Rents DROP COLUMN IF EXISTS late_fee;
+ALTER TABLE Rents DROP COLUMN IF EXISTS returned_at;
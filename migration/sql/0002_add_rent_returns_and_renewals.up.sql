@@ -0,0 +1,3 @@
+ALTER TABLE Rents ADD COLUMN IF NOT EXISTS returned_at TIMESTAMPTZ;
+ALTER TABLE Rents ADD COLUMN IF NOT EXISTS late_fee DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE Rents ADD COLUMN IF NOT EXISTS renewal_count INT NOT NULL DEFAULT 0;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS Reservations (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) NOT NULL,
+	book_id INT REFERENCES Books(id) NOT NULL,
+	position INT NOT NULL,
+	status VARCHAR NOT NULL DEFAULT 'waiting',
+	hold_expires_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reservations_active_user_book_idx ON Reservations (user_id, book_id) WHERE status IN ('waiting', 'held');
+
+DROP TRIGGER IF EXISTS update_reservation_modtime ON Reservations;
+CREATE TRIGGER update_reservation_modtime
+BEFORE UPDATE ON Reservations
+FOR EACH ROW
+EXECUTE FUNCTION update_modified_column();
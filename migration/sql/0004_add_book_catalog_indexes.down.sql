@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS books_search_vector_idx;
+ALTER TABLE Books DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS books_price_idx;
+DROP INDEX IF EXISTS books_genre_idx;
@@ -0,0 +1,10 @@
+CREATE INDEX IF NOT EXISTS books_genre_idx ON Books (genre);
+CREATE INDEX IF NOT EXISTS books_price_idx ON Books (price);
+
+ALTER TABLE Books ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', name), 'A') ||
+	setweight(to_tsvector('simple', author), 'B') ||
+	setweight(to_tsvector('simple', genre), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS books_search_vector_idx ON Books USING GIN (search_vector);
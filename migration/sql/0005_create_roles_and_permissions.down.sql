@@ -0,0 +1,4 @@
+ALTER TABLE Users DROP CONSTRAINT IF EXISTS users_role_fkey;
+DROP TABLE IF EXISTS Role_Permissions;
+DROP TABLE IF EXISTS Permissions;
+DROP TABLE IF EXISTS Roles;
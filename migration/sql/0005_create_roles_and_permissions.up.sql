@@ -0,0 +1,47 @@
+CREATE TABLE Roles (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR NOT NULL UNIQUE,
+	description VARCHAR NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE Permissions (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR NOT NULL UNIQUE,
+	description VARCHAR NOT NULL DEFAULT ''
+);
+
+CREATE TABLE Role_Permissions (
+	role_id INT REFERENCES Roles(id) ON DELETE CASCADE NOT NULL,
+	permission_id INT REFERENCES Permissions(id) ON DELETE CASCADE NOT NULL,
+	PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TRIGGER update_role_modtime
+BEFORE UPDATE ON Roles
+FOR EACH ROW
+EXECUTE FUNCTION update_modified_column();
+
+INSERT INTO Permissions (name, description) VALUES
+	('books:write', 'Create, update and delete books'),
+	('rents:create', 'Rent books'),
+	('rents:read_all', 'List the rents of every user'),
+	('rents:manage', 'Return any rent'),
+	('reservations:create', 'Reserve books that are out of stock'),
+	('reservations:manage', 'View, reorder and cancel any reservation queue'),
+	('users:manage', 'Manage user accounts'),
+	('roles:manage', 'Create roles and grant permissions');
+
+INSERT INTO Roles (name, description) VALUES
+	('Admin', 'Library staff'),
+	('Customer', 'Library member');
+
+INSERT INTO Role_Permissions (role_id, permission_id)
+SELECT Roles.id, Permissions.id FROM Roles, Permissions WHERE Roles.name = 'Admin';
+
+INSERT INTO Role_Permissions (role_id, permission_id)
+SELECT Roles.id, Permissions.id FROM Roles, Permissions
+WHERE Roles.name = 'Customer' AND Permissions.name IN ('rents:create', 'reservations:create');
+
+ALTER TABLE Users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES Roles(name) ON UPDATE CASCADE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS Refresh_Tokens;
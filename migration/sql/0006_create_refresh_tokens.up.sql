@@ -0,0 +1,13 @@
+CREATE TABLE Refresh_Tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	family_id UUID NOT NULL,
+	token_hash VARCHAR NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ,
+	replaced_by INT REFERENCES Refresh_Tokens(id),
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON Refresh_Tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON Refresh_Tokens (user_id);
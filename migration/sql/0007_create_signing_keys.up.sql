@@ -0,0 +1,10 @@
+CREATE TABLE Signing_Keys (
+	kid VARCHAR PRIMARY KEY,
+	algorithm VARCHAR NOT NULL,
+	private_key TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	retired_at TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX signing_keys_algorithm_created_at_idx ON Signing_Keys (algorithm, created_at);
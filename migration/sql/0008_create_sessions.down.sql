@@ -0,0 +1,2 @@
+ALTER TABLE Refresh_Tokens DROP CONSTRAINT IF EXISTS refresh_tokens_family_id_fkey;
+DROP TABLE IF EXISTS Sessions;
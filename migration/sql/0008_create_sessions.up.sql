@@ -0,0 +1,26 @@
+CREATE TABLE Sessions (
+	id UUID PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	device VARCHAR NOT NULL DEFAULT '',
+	ip_address VARCHAR NOT NULL DEFAULT '',
+	user_agent VARCHAR NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX sessions_user_id_idx ON Sessions (user_id) WHERE revoked_at IS NULL;
+
+-- Every refresh token family started at a login, so each one becomes a
+-- session. Families without a live token are recorded as revoked.
+INSERT INTO Sessions (id, user_id, created_at, last_seen_at, revoked_at)
+SELECT family_id,
+	MIN(user_id),
+	MIN(created_at),
+	MAX(created_at),
+	CASE WHEN bool_or(revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP) THEN NULL ELSE MAX(COALESCE(revoked_at, expires_at)) END
+FROM Refresh_Tokens
+GROUP BY family_id;
+
+ALTER TABLE Refresh_Tokens
+	ADD CONSTRAINT refresh_tokens_family_id_fkey FOREIGN KEY (family_id) REFERENCES Sessions(id) ON DELETE CASCADE;
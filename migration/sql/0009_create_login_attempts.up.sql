@@ -0,0 +1,6 @@
+CREATE TABLE Login_Attempts (
+	key VARCHAR PRIMARY KEY,
+	failures INT NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMPTZ NOT NULL,
+	locked_until TIMESTAMPTZ
+);
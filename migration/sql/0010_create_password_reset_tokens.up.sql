@@ -0,0 +1,10 @@
+CREATE TABLE Password_Reset_Tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	token_hash VARCHAR NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON Password_Reset_Tokens (user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS Email_Verification_Tokens;
+ALTER TABLE Users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,15 @@
+ALTER TABLE Users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Accounts created before verification existed keep working.
+UPDATE Users SET email_verified_at = created_at;
+
+CREATE TABLE Email_Verification_Tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	token_hash VARCHAR NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON Email_Verification_Tokens (user_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS Login_Challenges;
+DROP TABLE IF EXISTS Recovery_Codes;
+
+ALTER TABLE Sessions DROP COLUMN IF EXISTS two_factor_verified;
+
+ALTER TABLE Users
+	DROP COLUMN IF EXISTS totp_secret,
+	DROP COLUMN IF EXISTS totp_enabled_at,
+	DROP COLUMN IF EXISTS totp_last_step;
@@ -0,0 +1,25 @@
+ALTER TABLE Users
+	ADD COLUMN totp_secret VARCHAR,
+	ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+	ADD COLUMN totp_last_step BIGINT;
+
+ALTER TABLE Sessions ADD COLUMN two_factor_verified BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE Recovery_Codes (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	code_hash VARCHAR NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE Login_Challenges (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	token_hash VARCHAR NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
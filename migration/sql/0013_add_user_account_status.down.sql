@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_role_idx;
+
+ALTER TABLE Users
+	DROP COLUMN IF EXISTS suspended_at,
+	DROP COLUMN IF EXISTS password_reset_required;
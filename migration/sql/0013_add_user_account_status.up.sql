@@ -0,0 +1,5 @@
+ALTER TABLE Users
+	ADD COLUMN suspended_at TIMESTAMPTZ,
+	ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX users_role_idx ON Users (role);
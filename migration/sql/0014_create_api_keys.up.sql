@@ -0,0 +1,15 @@
+CREATE TABLE Api_Keys (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	name VARCHAR NOT NULL,
+	key_prefix VARCHAR NOT NULL,
+	key_hash VARCHAR NOT NULL UNIQUE,
+	scopes VARCHAR[] NOT NULL DEFAULT '{}',
+	expires_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	last_used_ip VARCHAR,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_keys_user_id_idx ON Api_Keys (user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS OIDC_Login_States;
+
+DROP INDEX IF EXISTS users_oidc_subject_idx;
+
+ALTER TABLE Users
+	DROP COLUMN IF EXISTS oidc_issuer,
+	DROP COLUMN IF EXISTS oidc_subject;
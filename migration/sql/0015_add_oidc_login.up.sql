@@ -0,0 +1,15 @@
+ALTER TABLE Users
+	ADD COLUMN oidc_issuer VARCHAR,
+	ADD COLUMN oidc_subject VARCHAR;
+
+CREATE UNIQUE INDEX users_oidc_subject_idx ON Users (oidc_issuer, oidc_subject) WHERE oidc_subject IS NOT NULL;
+
+CREATE TABLE OIDC_Login_States (
+	id SERIAL PRIMARY KEY,
+	state_hash VARCHAR NOT NULL UNIQUE,
+	nonce VARCHAR NOT NULL,
+	code_verifier VARCHAR NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
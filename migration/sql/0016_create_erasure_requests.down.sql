@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS Erasure_Requests;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS anonymized_at;
@@ -0,0 +1,15 @@
+ALTER TABLE Users ADD COLUMN anonymized_at TIMESTAMPTZ;
+
+CREATE TABLE Erasure_Requests (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES Users(id) ON DELETE CASCADE NOT NULL,
+	status VARCHAR NOT NULL DEFAULT 'pending',
+	reason VARCHAR NOT NULL DEFAULT '',
+	note VARCHAR NOT NULL DEFAULT '',
+	processed_by INT REFERENCES Users(id) ON DELETE SET NULL,
+	processed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX erasure_requests_pending_idx ON Erasure_Requests (user_id) WHERE status = 'pending';
+CREATE INDEX erasure_requests_status_idx ON Erasure_Requests (status, created_at);
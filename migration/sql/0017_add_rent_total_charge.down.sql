@@ -0,0 +1 @@
+ALTER TABLE Rents DROP COLUMN IF EXISTS total_charge;
@@ -0,0 +1,3 @@
+ALTER TABLE Rents ADD COLUMN IF NOT EXISTS total_charge DECIMAL(10, 2);
+
+UPDATE Rents SET total_charge = total_price + late_fee WHERE returned_at IS NOT NULL;
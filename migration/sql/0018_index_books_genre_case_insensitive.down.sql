@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS books_genre_lower_idx;
+CREATE INDEX IF NOT EXISTS books_genre_idx ON Books (genre);
@@ -0,0 +1 @@
+DELETE FROM Permissions WHERE name IN ('books:read', 'rents:self', 'reservations:self');
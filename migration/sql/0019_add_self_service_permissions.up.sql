@@ -0,0 +1,11 @@
+INSERT INTO Permissions (name, description) VALUES
+	('books:read', 'Browse and search the book catalog'),
+	('rents:self', 'List, return and renew own rents'),
+	('reservations:self', 'List and cancel own reservations')
+ON CONFLICT (name) DO NOTHING;
+
+-- Every role could use these endpoints before they required a permission.
+INSERT INTO Role_Permissions (role_id, permission_id)
+SELECT Roles.id, Permissions.id FROM Roles, Permissions
+WHERE Permissions.name IN ('books:read', 'rents:self', 'reservations:self')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE Books DROP CONSTRAINT IF EXISTS books_published_date_format;
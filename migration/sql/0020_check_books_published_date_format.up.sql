@@ -0,0 +1,3 @@
+-- Dates are stored as YYYY-MM-DD text so they sort chronologically. Rows
+-- written before the check are not validated.
+ALTER TABLE Books ADD CONSTRAINT books_published_date_format CHECK (published_date ~ '^\d{4}-\d{2}-\d{2}$') NOT VALID;
@@ -0,0 +1,42 @@
+package notification
+
+import (
+	"dgw-technical-test/entity"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/repository"
+	"fmt"
+)
+
+// MailNotifier emails the customer a reservation belongs to.
+type MailNotifier struct {
+	Mailer         mailer.Mailer
+	UserRepository repository.UserRepository
+	BookRepository repository.BookRepository
+}
+
+func NewMailNotifier(mailSender mailer.Mailer, userRepository repository.UserRepository, bookRepository repository.BookRepository) *MailNotifier {
+	return &MailNotifier{
+		Mailer:         mailSender,
+		UserRepository: userRepository,
+		BookRepository: bookRepository,
+	}
+}
+
+func (notifier *MailNotifier) ReservationHeld(reservation entity.Reservation) error {
+	user, err := notifier.UserRepository.FindById(reservation.UserID)
+	if err != nil {
+		return err
+	}
+
+	book, err := notifier.BookRepository.FindById(reservation.BookID)
+	if err != nil {
+		return err
+	}
+
+	return notifier.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your reserved book is ready",
+		Body: fmt.Sprintf("Hi %s,\n\nA copy of %q is now held for you. Rent it before %s, after which it goes to the next customer in line.\n",
+			user.Username, book.Name, reservation.HoldExpiresAt.Format("2006-01-02 15:04 MST")),
+	})
+}
@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"dgw-technical-test/config"
+	"dgw-technical-test/entity"
+	"dgw-technical-test/mailer"
+	"dgw-technical-test/repository"
+	"log"
+)
+
+type Notifier interface {
+	ReservationHeld(reservation entity.Reservation) error
+}
+
+// NewNotifier emails customers through the mailer, except with the log mail
+// driver used in development, where notifications are only logged.
+func NewNotifier(mailConfig *config.MailConfig, mailSender mailer.Mailer, userRepository repository.UserRepository, bookRepository repository.BookRepository) Notifier {
+	if mailConfig.Driver == "log" {
+		return NewLogNotifier()
+	}
+
+	return NewMailNotifier(mailSender, userRepository, bookRepository)
+}
+
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (notifier *LogNotifier) ReservationHeld(reservation entity.Reservation) error {
+	log.Printf("reservation %d: book %d is held for user %d until %s\n",
+		reservation.ID,
+		reservation.BookID,
+		reservation.UserID,
+		reservation.HoldExpiresAt.Format("2006-01-02 15:04:05 MST"),
+	)
+
+	return nil
+}
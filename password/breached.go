@@ -0,0 +1,66 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedList looks passwords up in a local copy of a breached password
+// corpus split by SHA-1 prefix, the format of the Pwned Passwords range API.
+// Dir holds one file per 5 character uppercase hex prefix, named after the
+// prefix with an optional .txt extension, whose lines are the remaining 35
+// characters of a hash followed by ":" and a count. Only the file of the
+// prefix is read, so the corpus never has to fit in memory.
+type BreachedList struct {
+	Dir string
+}
+
+func NewBreachedList(dir string) *BreachedList {
+	return &BreachedList{Dir: dir}
+}
+
+// Contains reports whether the password appears in the list. A list without
+// a directory contains nothing.
+func (list *BreachedList) Contains(password string) (bool, error) {
+	if list.Dir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := list.open(prefix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+func (list *BreachedList) open(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(list.Dir, prefix))
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Open(filepath.Join(list.Dir, prefix+".txt"))
+	}
+
+	return file, err
+}
@@ -0,0 +1,87 @@
+package password
+
+import (
+	"dgw-technical-test/config"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Checker applies the password policy and the breached password list.
+type Checker struct {
+	Policy   *config.PasswordPolicy
+	Breached *BreachedList
+}
+
+func NewChecker(policy *config.PasswordPolicy) *Checker {
+	return &Checker{
+		Policy:   policy,
+		Breached: NewBreachedList(policy.BreachedDir),
+	}
+}
+
+// Check returns every rule the password breaks, or none when it is
+// acceptable for the user with the given username and email. The error is
+// only set when the breached password list could not be read.
+func (checker *Checker) Check(password string, username string, email string) ([]string, error) {
+	policy := checker.Policy
+	violations := []string{}
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		violations = append(violations, fmt.Sprintf("password must be at least %d characters long", policy.MinLength))
+	}
+
+	if len(password) > policy.MaxLength {
+		violations = append(violations, fmt.Sprintf("password must be at most %d bytes long", policy.MaxLength))
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if policy.RequireLowercase && !hasLower {
+		violations = append(violations, "password must contain a lowercase letter")
+	}
+
+	if policy.RequireUppercase && !hasUpper {
+		violations = append(violations, "password must contain an uppercase letter")
+	}
+
+	if policy.RequireDigit && !hasDigit {
+		violations = append(violations, "password must contain a digit")
+	}
+
+	if policy.RequireSymbol && !hasSymbol {
+		violations = append(violations, "password must contain a symbol")
+	}
+
+	localPart, _, _ := strings.Cut(email, "@")
+	for _, identifier := range []string{username, email, localPart} {
+		if identifier != "" && strings.EqualFold(password, identifier) {
+			violations = append(violations, "password must not be the same as the username or email")
+			break
+		}
+	}
+
+	breached, err := checker.Breached.Contains(password)
+	if err != nil {
+		return nil, err
+	}
+
+	if breached {
+		violations = append(violations, "password has appeared in a data breach, choose a different one")
+	}
+
+	return violations, nil
+}
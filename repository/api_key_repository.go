@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ApiKeyRepository interface {
+	Create(apiKey *entity.ApiKey) error
+	Touch(apiKeyId int, usedAt time.Time, ip string) error
+	Revoke(apiKeyId int) error
+	FindByHash(keyHash string) (*entity.ApiKey, error)
+	FindById(apiKeyId int) (*entity.ApiKey, error)
+	FindActiveByUserId(userId int) ([]entity.ApiKey, error)
+	FindByUserId(userId int) ([]entity.ApiKey, error)
+	CountActiveByUserId(userId int) (int, error)
+}
+
+type ApiKeyRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewApiKeyRepository(db *sqlx.DB) *ApiKeyRepositoryImpl {
+	return &ApiKeyRepositoryImpl{DB: db}
+}
+
+func (repository *ApiKeyRepositoryImpl) Create(apiKey *entity.ApiKey) error {
+	query := "INSERT INTO Api_Keys (user_id, name, key_prefix, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at"
+
+	if err := repository.DB.QueryRow(query, apiKey.UserID, apiKey.Name, apiKey.KeyPrefix, apiKey.KeyHash, apiKey.Scopes, apiKey.ExpiresAt).Scan(&apiKey.ID, &apiKey.CreatedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ApiKeyRepositoryImpl) Touch(apiKeyId int, usedAt time.Time, ip string) error {
+	query := "UPDATE Api_Keys SET last_used_at = $1, last_used_ip = $2 WHERE id = $3 AND (last_used_at IS NULL OR last_used_at < $1)"
+
+	_, err := repository.DB.Exec(query, usedAt, ip, apiKeyId)
+	return err
+}
+
+func (repository *ApiKeyRepositoryImpl) Revoke(apiKeyId int) error {
+	query := "UPDATE Api_Keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL"
+
+	_, err := repository.DB.Exec(query, apiKeyId)
+	return err
+}
+
+func (repository *ApiKeyRepositoryImpl) FindByHash(keyHash string) (*entity.ApiKey, error) {
+	query := "SELECT * FROM Api_Keys WHERE key_hash = $1"
+
+	apiKey := new(entity.ApiKey)
+	if err := repository.DB.Get(apiKey, query, keyHash); err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func (repository *ApiKeyRepositoryImpl) FindById(apiKeyId int) (*entity.ApiKey, error) {
+	query := "SELECT * FROM Api_Keys WHERE id = $1"
+
+	apiKey := new(entity.ApiKey)
+	if err := repository.DB.Get(apiKey, query, apiKeyId); err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// FindActiveByUserId returns the keys of the user that are neither revoked
+// nor expired, newest first.
+func (repository *ApiKeyRepositoryImpl) FindActiveByUserId(userId int) ([]entity.ApiKey, error) {
+	query := "SELECT * FROM Api_Keys WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP ORDER BY created_at DESC, id DESC"
+
+	apiKeys := []entity.ApiKey{}
+	if err := repository.DB.Select(&apiKeys, query, userId); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (repository *ApiKeyRepositoryImpl) FindByUserId(userId int) ([]entity.ApiKey, error) {
+	query := "SELECT * FROM Api_Keys WHERE user_id = $1 ORDER BY created_at DESC, id DESC"
+
+	apiKeys := []entity.ApiKey{}
+	if err := repository.DB.Select(&apiKeys, query, userId); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (repository *ApiKeyRepositoryImpl) CountActiveByUserId(userId int) (int, error) {
+	query := "SELECT COUNT(*) FROM Api_Keys WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP"
+
+	var count int
+	if err := repository.DB.Get(&count, query, userId); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
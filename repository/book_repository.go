@@ -3,16 +3,46 @@ package repository
 import (
 	"database/sql"
 	"dgw-technical-test/entity"
+	"fmt"
+	"strings"
+	"unicode"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// BookFilter narrows down and orders the books returned by FindAll. Zero
+// values leave the corresponding filter out.
+type BookFilter struct {
+	Genre    string
+	Author   string
+	MinPrice *float64
+	MaxPrice *float64
+	InStock  bool
+	Sort     string
+	Order    string
+	Limit    int
+	Offset   int
+}
+
+// bookSortColumns maps sort fields to columns. published_date is a VARCHAR
+// holding YYYY-MM-DD dates, which sort chronologically as text.
+var bookSortColumns = map[string]string{
+	"name":           "name",
+	"price":          "price",
+	"published_date": "published_date",
+}
+
+// bookColumns lists the columns of entity.Book so queries do not pick up the
+// generated search_vector column.
+const bookColumns = "id, name, genre, author, published_date, stock, price, created_at, updated_at"
+
 type BookRepository interface {
 	Create(book *entity.Book) error
 	Update(book *entity.Book) error
 	Delete(bookId int) error
-	FindAll() ([]entity.Book, error)
+	FindAll(filter BookFilter) ([]entity.Book, int, error)
 	FindById(bookId int) (*entity.Book, error)
+	Search(text string, limit int, offset int) ([]entity.BookSearchResult, int, error)
 }
 
 type BookRepositoryImpl struct {
@@ -59,19 +89,69 @@ func (repository *BookRepositoryImpl) Delete(bookId int) error {
 	return nil
 }
 
-func (repository *BookRepositoryImpl) FindAll() ([]entity.Book, error) {
-	query := "SELECT * FROM Books"
+// FindAll returns one page of the books matching the filter together with
+// the total number of matching books.
+func (repository *BookRepositoryImpl) FindAll(filter BookFilter) ([]entity.Book, int, error) {
+	var conditions []string
+	var args []interface{}
 
-	var books []entity.Book
-	if err := repository.DB.Select(&books, query); err != nil {
-		return nil, err
+	if filter.Genre != "" {
+		args = append(args, filter.Genre)
+		conditions = append(conditions, fmt.Sprintf("LOWER(genre) = LOWER($%d)", len(args)))
+	}
+
+	if filter.Author != "" {
+		args = append(args, "%"+escapeLike(filter.Author)+"%")
+		conditions = append(conditions, fmt.Sprintf("author ILIKE $%d", len(args)))
+	}
+
+	if filter.MinPrice != nil {
+		args = append(args, *filter.MinPrice)
+		conditions = append(conditions, fmt.Sprintf("price >= $%d", len(args)))
+	}
+
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		conditions = append(conditions, fmt.Sprintf("price <= $%d", len(args)))
+	}
+
+	if filter.InStock {
+		conditions = append(conditions, "stock > 0")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := repository.DB.Get(&total, "SELECT COUNT(*) FROM Books"+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	sortColumn, ok := bookSortColumns[filter.Sort]
+	if !ok {
+		sortColumn = "id"
 	}
 
-	return books, nil
+	order := "ASC"
+	if strings.EqualFold(filter.Order, "desc") {
+		order = "DESC"
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf("SELECT %s FROM Books%s ORDER BY %s %s, id LIMIT $%d OFFSET $%d", bookColumns, where, sortColumn, order, len(args)-1, len(args))
+
+	books := []entity.Book{}
+	if err := repository.DB.Select(&books, query, args...); err != nil {
+		return nil, 0, err
+	}
+
+	return books, total, nil
 }
 
 func (repository *BookRepositoryImpl) FindById(bookId int) (*entity.Book, error) {
-	query := "SELECT * FROM Books WHERE id = $1"
+	query := "SELECT " + bookColumns + " FROM Books WHERE id = $1"
 
 	book := new(entity.Book)
 	if err := repository.DB.Get(book, query, bookId); err != nil {
@@ -80,3 +160,57 @@ func (repository *BookRepositoryImpl) FindById(bookId int) (*entity.Book, error)
 
 	return book, nil
 }
+
+// Search runs a full-text search over the name, author and genre of the books.
+// Every word of text is matched as a prefix, results are ordered by relevance
+// and the matching words are highlighted in the snippet.
+func (repository *BookRepositoryImpl) Search(text string, limit int, offset int) ([]entity.BookSearchResult, int, error) {
+	tsQuery := prefixTsQuery(text)
+	if tsQuery == "" {
+		return []entity.BookSearchResult{}, 0, nil
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM Books WHERE search_vector @@ to_tsquery('simple', $1)"
+	if err := repository.DB.Get(&total, countQuery, tsQuery); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + bookColumns + `,
+		ts_rank(search_vector, query) AS rank,
+		ts_headline('simple', name || ' - ' || author || ' - ' || genre, query, 'StartSel=<mark>, StopSel=</mark>') AS snippet
+		FROM Books, to_tsquery('simple', $1) query
+		WHERE search_vector @@ query
+		ORDER BY rank DESC, id
+		LIMIT $2 OFFSET $3`
+
+	results := []entity.BookSearchResult{}
+	if err := repository.DB.Select(&results, query, tsQuery, limit, offset); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// prefixTsQuery turns free text into a tsquery that requires every word as a
+// prefix, e.g. "harry pot" becomes "harry:* & pot:*". Characters with a
+// meaning in tsquery syntax are dropped.
+func prefixTsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, strings.ToLower(word)+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes % and _ in user input match literally in a LIKE pattern.
+func escapeLike(text string) string {
+	return likeEscaper.Replace(text)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrVerificationTokenUsed = errors.New("email verification token has already been used")
+
+type EmailVerificationRepository interface {
+	Create(token *entity.EmailVerificationToken) error
+	Consume(token *entity.EmailVerificationToken) error
+	FindByHash(tokenHash string) (*entity.EmailVerificationToken, error)
+	FindLatestByUserId(userId int) (*entity.EmailVerificationToken, error)
+}
+
+type EmailVerificationRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewEmailVerificationRepository(db *sqlx.DB) *EmailVerificationRepositoryImpl {
+	return &EmailVerificationRepositoryImpl{DB: db}
+}
+
+// Create stores the token and invalidates the earlier unused tokens of the
+// user, so only the latest verification email works.
+func (repository *EmailVerificationRepositoryImpl) Create(token *entity.EmailVerificationToken) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE Email_Verification_Tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL", token.UserID); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO Email_Verification_Tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at"
+	if err := tx.QueryRow(query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Consume marks the token used and the email of its user verified. It fails
+// with ErrVerificationTokenUsed when the token was already used.
+func (repository *EmailVerificationRepositoryImpl) Consume(token *entity.EmailVerificationToken) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE Email_Verification_Tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL", token.ID)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrVerificationTokenUsed
+	}
+
+	if _, err := tx.Exec("UPDATE Users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = $1 AND email_verified_at IS NULL", token.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *EmailVerificationRepositoryImpl) FindByHash(tokenHash string) (*entity.EmailVerificationToken, error) {
+	query := "SELECT * FROM Email_Verification_Tokens WHERE token_hash = $1"
+
+	token := new(entity.EmailVerificationToken)
+	if err := repository.DB.Get(token, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (repository *EmailVerificationRepositoryImpl) FindLatestByUserId(userId int) (*entity.EmailVerificationToken, error) {
+	query := "SELECT * FROM Email_Verification_Tokens WHERE user_id = $1 ORDER BY created_at DESC LIMIT 1"
+
+	token := new(entity.EmailVerificationToken)
+	if err := repository.DB.Get(token, query, userId); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
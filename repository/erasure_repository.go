@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"dgw-technical-test/entity"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrErasurePending            = errors.New("an erasure request is already pending")
+	ErrErasureClosed             = errors.New("erasure request has already been processed")
+	ErrErasureActiveRentals      = errors.New("user still has unreturned rentals")
+	ErrErasureActiveReservations = errors.New("user still has active reservations")
+	ErrAccountErased             = errors.New("account has been erased")
+)
+
+type ErasureRepository interface {
+	Create(request *entity.ErasureRequest) error
+	Complete(requestId int, adminId int) error
+	Reject(requestId int, adminId int, note string) error
+	FindById(requestId int) (*entity.ErasureRequest, error)
+	FindByStatus(status string) ([]entity.ErasureRequest, error)
+	FindByUserId(userId int) ([]entity.ErasureRequest, error)
+}
+
+type ErasureRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewErasureRepository(db *sqlx.DB) *ErasureRepositoryImpl {
+	return &ErasureRepositoryImpl{DB: db}
+}
+
+// Create stores a pending request. It fails with ErrErasurePending when the
+// user already has one.
+func (repository *ErasureRepositoryImpl) Create(request *entity.ErasureRequest) error {
+	query := "INSERT INTO Erasure_Requests (user_id, reason) VALUES ($1, $2) RETURNING id, status, created_at"
+
+	if err := repository.DB.QueryRow(query, request.UserID, request.Reason).Scan(&request.ID, &request.Status, &request.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrErasurePending
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Complete anonymizes the user of a pending request. The Users row stays so
+// rents keep pointing at it for accounting, but everything identifying the
+// person is overwritten, the account can no longer log in and every
+// credential, session and pending token of the user is deleted. The user row
+// is locked before checking for unreturned rentals and active reservations,
+// so none can be created until the erasure is done; those fail with
+// ErrErasureActiveRentals and ErrErasureActiveReservations, and erasing the
+// last active admin fails with ErrLastAdmin.
+func (repository *ErasureRepositoryImpl) Complete(requestId int, adminId int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userId int
+	query := "UPDATE Erasure_Requests SET status = $1, processed_by = $2, processed_at = CURRENT_TIMESTAMP WHERE id = $3 AND status = $4 RETURNING user_id"
+	if err := tx.QueryRow(query, entity.ErasureStatusCompleted, adminId, requestId, entity.ErasureStatusPending).Scan(&userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrErasureClosed
+		}
+		return err
+	}
+
+	if err := guardLastAdmin(tx, userId); err != nil {
+		return err
+	}
+
+	var username string
+	if err := tx.Get(&username, "SELECT username FROM Users WHERE id = $1 FOR UPDATE", userId); err != nil {
+		return err
+	}
+
+	var rents int
+	if err := tx.Get(&rents, "SELECT COUNT(*) FROM Rents WHERE user_id = $1 AND returned_at IS NULL", userId); err != nil {
+		return err
+	}
+
+	if rents > 0 {
+		return ErrErasureActiveRentals
+	}
+
+	var reservations int
+	reservationsQuery := "SELECT COUNT(*) FROM Reservations WHERE user_id = $1 AND status IN ($2, $3)"
+	if err := tx.Get(&reservations, reservationsQuery, userId, entity.ReservationStatusWaiting, entity.ReservationStatusHeld); err != nil {
+		return err
+	}
+
+	if reservations > 0 {
+		return ErrErasureActiveReservations
+	}
+
+	// The password is not a valid hash, so no password can match it.
+	query = `UPDATE Users SET
+		username = $1, email = $2, password = '', email_verified_at = NULL,
+		totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL,
+		oidc_issuer = NULL, oidc_subject = NULL, password_reset_required = false,
+		suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP), anonymized_at = CURRENT_TIMESTAMP
+		WHERE id = $3`
+	if _, err := tx.Exec(query, fmt.Sprintf("deleted-user-%d", userId), fmt.Sprintf("deleted-user-%d@invalid", userId), userId); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"Sessions", "Api_Keys", "Recovery_Codes", "Login_Challenges", "Password_Reset_Tokens", "Email_Verification_Tokens"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE user_id = $1", userId); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM Login_Attempts WHERE key = $1", "account:"+username); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *ErasureRepositoryImpl) Reject(requestId int, adminId int, note string) error {
+	query := "UPDATE Erasure_Requests SET status = $1, note = $2, processed_by = $3, processed_at = CURRENT_TIMESTAMP WHERE id = $4 AND status = $5"
+
+	result, err := repository.DB.Exec(query, entity.ErasureStatusRejected, note, adminId, requestId, entity.ErasureStatusPending)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrErasureClosed
+	}
+
+	return nil
+}
+
+func (repository *ErasureRepositoryImpl) FindById(requestId int) (*entity.ErasureRequest, error) {
+	query := "SELECT * FROM Erasure_Requests WHERE id = $1"
+
+	request := new(entity.ErasureRequest)
+	if err := repository.DB.Get(request, query, requestId); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// FindByStatus returns the requests with the status, oldest first.
+func (repository *ErasureRepositoryImpl) FindByStatus(status string) ([]entity.ErasureRequest, error) {
+	query := "SELECT * FROM Erasure_Requests WHERE status = $1 ORDER BY created_at, id"
+
+	requests := []entity.ErasureRequest{}
+	if err := repository.DB.Select(&requests, query, status); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+func (repository *ErasureRepositoryImpl) FindByUserId(userId int) ([]entity.ErasureRequest, error) {
+	query := "SELECT * FROM Erasure_Requests WHERE user_id = $1 ORDER BY created_at, id"
+
+	requests := []entity.ErasureRequest{}
+	if err := repository.DB.Select(&requests, query, userId); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// lockOpenAccount share-locks the row of the user, which waits for an erasure
+// in progress, and fails with ErrAccountErased once the user is anonymized.
+func lockOpenAccount(tx *sqlx.Tx, userId int) error {
+	var anonymizedAt *time.Time
+	if err := tx.Get(&anonymizedAt, "SELECT anonymized_at FROM Users WHERE id = $1 FOR SHARE", userId); err != nil {
+		return err
+	}
+
+	if anonymizedAt != nil {
+		return ErrAccountErased
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// LoginAttemptRepository counts failed logins per key, e.g. an account or a
+// client IP.
+type LoginAttemptRepository interface {
+	RecordFailure(key string, now time.Time, windowStart time.Time) (int, error)
+	Lock(key string, until time.Time) error
+	Clear(key string) error
+	FindLockedUntil(keys []string, now time.Time) (*time.Time, error)
+}
+
+type LoginAttemptRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB) *LoginAttemptRepositoryImpl {
+	return &LoginAttemptRepositoryImpl{DB: db}
+}
+
+// RecordFailure counts a failed login for key and returns the failures so
+// far. The count starts over when the key saw no failure nor lockout since
+// windowStart.
+func (repository *LoginAttemptRepositoryImpl) RecordFailure(key string, now time.Time, windowStart time.Time) (int, error) {
+	query := `INSERT INTO Login_Attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN GREATEST(Login_Attempts.last_failure_at, Login_Attempts.locked_until) < $3 THEN 1 ELSE Login_Attempts.failures + 1 END,
+			last_failure_at = $2
+		RETURNING failures`
+
+	var failures int
+	if err := repository.DB.QueryRow(query, key, now, windowStart).Scan(&failures); err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+func (repository *LoginAttemptRepositoryImpl) Lock(key string, until time.Time) error {
+	query := "UPDATE Login_Attempts SET locked_until = $1 WHERE key = $2"
+
+	_, err := repository.DB.Exec(query, until, key)
+	return err
+}
+
+func (repository *LoginAttemptRepositoryImpl) Clear(key string) error {
+	query := "DELETE FROM Login_Attempts WHERE key = $1"
+
+	_, err := repository.DB.Exec(query, key)
+	return err
+}
+
+// FindLockedUntil returns the latest lockout among keys, or nil when none of
+// them is locked at now.
+func (repository *LoginAttemptRepositoryImpl) FindLockedUntil(keys []string, now time.Time) (*time.Time, error) {
+	query := "SELECT MAX(locked_until) FROM Login_Attempts WHERE key = ANY($1) AND locked_until > $2"
+
+	var lockedUntil sql.NullTime
+	if err := repository.DB.QueryRow(query, pq.Array(keys), now).Scan(&lockedUntil); err != nil {
+		return nil, err
+	}
+
+	if !lockedUntil.Valid {
+		return nil, nil
+	}
+
+	return &lockedUntil.Time, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrIdentityLinked = errors.New("account is already linked to another identity")
+
+type OIDCRepository interface {
+	CreateState(state *entity.OIDCLoginState) error
+	ConsumeState(stateHash string) (*entity.OIDCLoginState, error)
+	FindUserBySubject(issuer string, subject string) (*entity.User, error)
+	LinkUser(userId int, issuer string, subject string) error
+}
+
+type OIDCRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewOIDCRepository(db *sqlx.DB) *OIDCRepositoryImpl {
+	return &OIDCRepositoryImpl{DB: db}
+}
+
+func (repository *OIDCRepositoryImpl) CreateState(state *entity.OIDCLoginState) error {
+	query := "INSERT INTO OIDC_Login_States (state_hash, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+
+	if err := repository.DB.QueryRow(query, state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt).Scan(&state.ID, &state.CreatedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeState marks the state used and returns it, so each callback can only
+// be redeemed once. Unknown, used and expired states fail with
+// sql.ErrNoRows.
+func (repository *OIDCRepositoryImpl) ConsumeState(stateHash string) (*entity.OIDCLoginState, error) {
+	query := "UPDATE OIDC_Login_States SET used_at = CURRENT_TIMESTAMP WHERE state_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP RETURNING *"
+
+	state := new(entity.OIDCLoginState)
+	if err := repository.DB.Get(state, query, stateHash); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (repository *OIDCRepositoryImpl) FindUserBySubject(issuer string, subject string) (*entity.User, error) {
+	query := "SELECT * FROM Users WHERE oidc_issuer = $1 AND oidc_subject = $2"
+
+	user := new(entity.User)
+	if err := repository.DB.Get(user, query, issuer, subject); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// LinkUser ties the user to an identity of the provider. It fails with
+// ErrIdentityLinked when either side is already linked elsewhere.
+func (repository *OIDCRepositoryImpl) LinkUser(userId int, issuer string, subject string) error {
+	query := "UPDATE Users SET oidc_issuer = $1, oidc_subject = $2 WHERE id = $3 AND (oidc_subject IS NULL OR (oidc_issuer = $1 AND oidc_subject = $2))"
+
+	result, err := repository.DB.Exec(query, issuer, subject, userId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrIdentityLinked
+		}
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrIdentityLinked
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrResetTokenUsed = errors.New("password reset token has already been used")
+
+type PasswordResetRepository interface {
+	Create(token *entity.PasswordResetToken) error
+	Consume(token *entity.PasswordResetToken, passwordHash string) error
+	FindByHash(tokenHash string) (*entity.PasswordResetToken, error)
+}
+
+type PasswordResetRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewPasswordResetRepository(db *sqlx.DB) *PasswordResetRepositoryImpl {
+	return &PasswordResetRepositoryImpl{DB: db}
+}
+
+// Create stores the token and invalidates the earlier unused tokens of the
+// user, so only the latest reset email works.
+func (repository *PasswordResetRepositoryImpl) Create(token *entity.PasswordResetToken) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE Password_Reset_Tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL", token.UserID); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO Password_Reset_Tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at"
+	if err := tx.QueryRow(query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Consume marks the token used and sets the new password of its user. Every
+// session of the user is revoked, so a stolen session does not survive the
+// reset. It fails with ErrResetTokenUsed when the token was already used.
+func (repository *PasswordResetRepositoryImpl) Consume(token *entity.PasswordResetToken, passwordHash string) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE Password_Reset_Tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL", token.ID)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrResetTokenUsed
+	}
+
+	if _, err := tx.Exec("UPDATE Users SET password = $1, password_reset_required = false WHERE id = $2", passwordHash, token.UserID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE Sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", token.UserID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE Refresh_Tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", token.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *PasswordResetRepositoryImpl) FindByHash(tokenHash string) (*entity.PasswordResetToken, error) {
+	query := "SELECT * FROM Password_Reset_Tokens WHERE token_hash = $1"
+
+	token := new(entity.PasswordResetToken)
+	if err := repository.DB.Get(token, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrRefreshTokenRevoked = errors.New("refresh token has been revoked")
+
+type RefreshTokenRepository interface {
+	Create(token *entity.RefreshToken) error
+	Rotate(previous *entity.RefreshToken, next *entity.RefreshToken) error
+	FindByHash(tokenHash string) (*entity.RefreshToken, error)
+}
+
+type RefreshTokenRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewRefreshTokenRepository(db *sqlx.DB) *RefreshTokenRepositoryImpl {
+	return &RefreshTokenRepositoryImpl{DB: db}
+}
+
+func (repository *RefreshTokenRepositoryImpl) Create(token *entity.RefreshToken) error {
+	query := "INSERT INTO Refresh_Tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+
+	if err := repository.DB.QueryRow(query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Rotate stores next and revokes previous in its favour. It fails with
+// ErrRefreshTokenRevoked when previous was already used, e.g. by a
+// concurrent request presenting the same token.
+func (repository *RefreshTokenRepositoryImpl) Rotate(previous *entity.RefreshToken, next *entity.RefreshToken) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insertQuery := "INSERT INTO Refresh_Tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	if err := tx.QueryRow(insertQuery, next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt).Scan(&next.ID, &next.CreatedAt); err != nil {
+		return err
+	}
+
+	revokeQuery := "UPDATE Refresh_Tokens SET revoked_at = CURRENT_TIMESTAMP, replaced_by = $1 WHERE id = $2 AND revoked_at IS NULL"
+	result, err := tx.Exec(revokeQuery, next.ID, previous.ID)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRefreshTokenRevoked
+	}
+
+	return tx.Commit()
+}
+
+func (repository *RefreshTokenRepositoryImpl) FindByHash(tokenHash string) (*entity.RefreshToken, error) {
+	query := "SELECT * FROM Refresh_Tokens WHERE token_hash = $1"
+
+	token := new(entity.RefreshToken)
+	if err := repository.DB.Get(token, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrOutOfStock      = errors.New("book is out of stock")
+	ErrAlreadyReturned = errors.New("rent has already been returned")
+	ErrRentChanged     = errors.New("rent was modified concurrently")
+	ErrBookWaitlisted  = errors.New("other customers are waiting for this book")
+)
+
+type RentRepository interface {
+	Create(rent *entity.Rent) error
+	Return(rent *entity.Rent, holdUntil time.Time) (*entity.Reservation, error)
+	Renew(rent *entity.Rent, previousRenewalCount int) error
+	FindAll() ([]entity.Rent, error)
+	FindById(rentId int) (*entity.Rent, error)
+	FindActiveByUserId(userId int) ([]entity.Rent, error)
+	FindByUserId(userId int) ([]entity.Rent, error)
+}
+
+type RentRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewRentRepository(db *sqlx.DB) *RentRepositoryImpl {
+	return &RentRepositoryImpl{DB: db}
+}
+
+// Create takes one copy of the book out of stock and records the rent in a
+// single transaction. The conditional update makes concurrent rentals of the
+// last copy fail with ErrOutOfStock instead of overselling. A customer holding
+// a reserved copy rents that copy instead, leaving the stock untouched.
+// Erased accounts fail with ErrAccountErased.
+func (repository *RentRepositoryImpl) Create(rent *entity.Rent) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenAccount(tx, rent.UserID); err != nil {
+		return err
+	}
+
+	holdQuery := "UPDATE Reservations SET status = $1 WHERE user_id = $2 AND book_id = $3 AND status = $4 AND hold_expires_at > CURRENT_TIMESTAMP"
+	result, err := tx.Exec(holdQuery, entity.ReservationStatusFulfilled, rent.UserID, rent.BookID, entity.ReservationStatusHeld)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		result, err := tx.Exec("UPDATE Books SET stock = stock - 1 WHERE id = $1 AND stock > 0", rent.BookID)
+		if err != nil {
+			return err
+		}
+
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrOutOfStock
+		}
+	}
+
+	query := "INSERT INTO Rents (user_id, book_id, total_price, start_date, end_date) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+
+	if err := tx.QueryRow(query, rent.UserID, rent.BookID, rent.TotalPrice, rent.StartDate, rent.EndDate).Scan(&rent.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Return records the return time, late fee and final charge of the rent and
+// releases the copy in a single transaction. The copy is held for the first
+// customer on the book's waitlist, who is returned so they can be notified;
+// otherwise it goes back into stock.
+func (repository *RentRepositoryImpl) Return(rent *entity.Rent, holdUntil time.Time) (*entity.Reservation, error) {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE Rents SET returned_at = $1, late_fee = $2, total_charge = $3 WHERE id = $4 AND returned_at IS NULL", rent.ReturnedAt, rent.LateFee, rent.TotalCharge, rent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrAlreadyReturned
+	}
+
+	held, err := releaseCopy(tx, rent.BookID, holdUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+// Renew stores the extended end date and price of the rent. The book row is
+// locked like when reserving, so nobody can join the waitlist between the
+// check and the update; renewals fail with ErrBookWaitlisted while someone is
+// waiting. The update only applies while the rent is still out and not
+// overdue, and nobody else renewed it in between.
+func (repository *RentRepositoryImpl) Renew(rent *entity.Rent, previousRenewalCount int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT id FROM Books WHERE id = $1 FOR UPDATE", rent.BookID); err != nil {
+		return err
+	}
+
+	var waiting int
+	waitingQuery := "SELECT COUNT(*) FROM Reservations WHERE book_id = $1 AND status = $2"
+	if err := tx.Get(&waiting, waitingQuery, rent.BookID, entity.ReservationStatusWaiting); err != nil {
+		return err
+	}
+
+	if waiting > 0 {
+		return ErrBookWaitlisted
+	}
+
+	query := "UPDATE Rents SET end_date = $1, total_price = $2, renewal_count = $3 WHERE id = $4 AND returned_at IS NULL AND renewal_count = $5 AND end_date >= CURRENT_TIMESTAMP"
+
+	result, err := tx.Exec(query, rent.EndDate, rent.TotalPrice, rent.RenewalCount, rent.ID, previousRenewalCount)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRentChanged
+	}
+
+	return tx.Commit()
+}
+
+func (repository *RentRepositoryImpl) FindAll() ([]entity.Rent, error) {
+	query := "SELECT * FROM Rents ORDER BY start_date DESC"
+
+	var rents []entity.Rent
+	if err := repository.DB.Select(&rents, query); err != nil {
+		return nil, err
+	}
+
+	return rents, nil
+}
+
+func (repository *RentRepositoryImpl) FindActiveByUserId(userId int) ([]entity.Rent, error) {
+	query := "SELECT * FROM Rents WHERE user_id = $1 AND returned_at IS NULL ORDER BY start_date DESC"
+
+	var rents []entity.Rent
+	if err := repository.DB.Select(&rents, query, userId); err != nil {
+		return nil, err
+	}
+
+	return rents, nil
+}
+
+func (repository *RentRepositoryImpl) FindByUserId(userId int) ([]entity.Rent, error) {
+	query := "SELECT * FROM Rents WHERE user_id = $1 ORDER BY start_date DESC"
+
+	rents := []entity.Rent{}
+	if err := repository.DB.Select(&rents, query, userId); err != nil {
+		return nil, err
+	}
+
+	return rents, nil
+}
+
+func (repository *RentRepositoryImpl) FindById(rentId int) (*entity.Rent, error) {
+	query := "SELECT * FROM Rents WHERE id = $1"
+
+	rent := new(entity.Rent)
+	if err := repository.DB.Get(rent, query, rentId); err != nil {
+		return nil, err
+	}
+
+	return rent, nil
+}
@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"database/sql"
+	"dgw-technical-test/entity"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAlreadyReserved       = errors.New("book is already reserved by this user")
+	ErrReservationClosed     = errors.New("reservation is no longer active")
+	ErrReservationNotInQueue = errors.New("reservation is not waiting in the queue")
+)
+
+type ReservationRepository interface {
+	Create(reservation *entity.Reservation) error
+	Cancel(reservation *entity.Reservation, holdUntil time.Time) (*entity.Reservation, error)
+	Move(reservation *entity.Reservation, position int) error
+	ExpireHolds(now time.Time, holdUntil time.Time) ([]entity.Reservation, error)
+	FindById(reservationId int) (*entity.Reservation, error)
+	FindActiveByUserId(userId int) ([]entity.Reservation, error)
+	FindByUserId(userId int) ([]entity.Reservation, error)
+	FindQueueByBookId(bookId int) ([]entity.Reservation, error)
+}
+
+type ReservationRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewReservationRepository(db *sqlx.DB) *ReservationRepositoryImpl {
+	return &ReservationRepositoryImpl{DB: db}
+}
+
+// Create appends the reservation to the end of the book's waiting queue. The
+// book row is locked so concurrent joins cannot take the same position.
+// Erased accounts fail with ErrAccountErased.
+func (repository *ReservationRepositoryImpl) Create(reservation *entity.Reservation) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenAccount(tx, reservation.UserID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("SELECT id FROM Books WHERE id = $1 FOR UPDATE", reservation.BookID); err != nil {
+		return err
+	}
+
+	positionQuery := "SELECT COALESCE(MAX(position), 0) + 1 FROM Reservations WHERE book_id = $1 AND status = $2"
+	if err := tx.Get(&reservation.Position, positionQuery, reservation.BookID, entity.ReservationStatusWaiting); err != nil {
+		return err
+	}
+
+	reservation.Status = entity.ReservationStatusWaiting
+
+	query := "INSERT INTO Reservations (user_id, book_id, position, status) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at"
+
+	if err := tx.QueryRow(query, reservation.UserID, reservation.BookID, reservation.Position, reservation.Status).Scan(&reservation.ID, &reservation.CreatedAt, &reservation.UpdatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrAlreadyReserved
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel withdraws the reservation. When it was holding a copy, the copy is
+// handed to the next customer in the queue, who is returned so they can be
+// notified.
+func (repository *ReservationRepositoryImpl) Cancel(reservation *entity.Reservation, holdUntil time.Time) (*entity.Reservation, error) {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var previousStatus string
+	if err := tx.Get(&previousStatus, "SELECT status FROM Reservations WHERE id = $1 FOR UPDATE", reservation.ID); err != nil {
+		return nil, err
+	}
+
+	if previousStatus != entity.ReservationStatusWaiting && previousStatus != entity.ReservationStatusHeld {
+		return nil, ErrReservationClosed
+	}
+
+	if _, err := tx.Exec("UPDATE Reservations SET status = $1 WHERE id = $2", entity.ReservationStatusCancelled, reservation.ID); err != nil {
+		return nil, err
+	}
+
+	var next *entity.Reservation
+	if previousStatus == entity.ReservationStatusHeld {
+		if next, err = releaseCopy(tx, reservation.BookID, holdUntil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	reservation.Status = entity.ReservationStatusCancelled
+	return next, nil
+}
+
+// Move places a waiting reservation at the given 1-based position of its
+// book's queue and renumbers the rest of the queue around it.
+func (repository *ReservationRepositoryImpl) Move(reservation *entity.Reservation, position int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT id FROM Books WHERE id = $1 FOR UPDATE", reservation.BookID); err != nil {
+		return err
+	}
+
+	var queue []int
+	query := "SELECT id FROM Reservations WHERE book_id = $1 AND status = $2 ORDER BY position"
+	if err := tx.Select(&queue, query, reservation.BookID, entity.ReservationStatusWaiting); err != nil {
+		return err
+	}
+
+	index := -1
+	for i, id := range queue {
+		if id == reservation.ID {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		return ErrReservationNotInQueue
+	}
+
+	queue = append(queue[:index], queue[index+1:]...)
+
+	if position < 1 {
+		position = 1
+	}
+	if position > len(queue)+1 {
+		position = len(queue) + 1
+	}
+
+	queue = append(queue[:position-1], append([]int{reservation.ID}, queue[position-1:]...)...)
+
+	for i, id := range queue {
+		if _, err := tx.Exec("UPDATE Reservations SET position = $1 WHERE id = $2", i+1, id); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	reservation.Position = position
+	return nil
+}
+
+// ExpireHolds closes every hold that ran out before now and passes each copy
+// on to the next customer waiting for the same book. The reservations that
+// received a copy are returned so they can be notified.
+func (repository *ReservationRepositoryImpl) ExpireHolds(now time.Time, holdUntil time.Time) ([]entity.Reservation, error) {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var expired []entity.Reservation
+	query := "SELECT * FROM Reservations WHERE status = $1 AND hold_expires_at <= $2 FOR UPDATE SKIP LOCKED"
+	if err := tx.Select(&expired, query, entity.ReservationStatusHeld, now); err != nil {
+		return nil, err
+	}
+
+	var held []entity.Reservation
+	for _, reservation := range expired {
+		if _, err := tx.Exec("UPDATE Reservations SET status = $1 WHERE id = $2", entity.ReservationStatusExpired, reservation.ID); err != nil {
+			return nil, err
+		}
+
+		next, err := releaseCopy(tx, reservation.BookID, holdUntil)
+		if err != nil {
+			return nil, err
+		}
+
+		if next != nil {
+			held = append(held, *next)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+func (repository *ReservationRepositoryImpl) FindById(reservationId int) (*entity.Reservation, error) {
+	query := "SELECT * FROM Reservations WHERE id = $1"
+
+	reservation := new(entity.Reservation)
+	if err := repository.DB.Get(reservation, query, reservationId); err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func (repository *ReservationRepositoryImpl) FindActiveByUserId(userId int) ([]entity.Reservation, error) {
+	query := "SELECT * FROM Reservations WHERE user_id = $1 AND status IN ($2, $3) ORDER BY created_at"
+
+	var reservations []entity.Reservation
+	if err := repository.DB.Select(&reservations, query, userId, entity.ReservationStatusWaiting, entity.ReservationStatusHeld); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+func (repository *ReservationRepositoryImpl) FindByUserId(userId int) ([]entity.Reservation, error) {
+	query := "SELECT * FROM Reservations WHERE user_id = $1 ORDER BY created_at"
+
+	reservations := []entity.Reservation{}
+	if err := repository.DB.Select(&reservations, query, userId); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+func (repository *ReservationRepositoryImpl) FindQueueByBookId(bookId int) ([]entity.Reservation, error) {
+	query := "SELECT * FROM Reservations WHERE book_id = $1 AND status IN ($2, $3) ORDER BY status = $3 DESC, position"
+
+	var reservations []entity.Reservation
+	if err := repository.DB.Select(&reservations, query, bookId, entity.ReservationStatusWaiting, entity.ReservationStatusHeld); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// releaseCopy hands a copy of the book that just became free to the first
+// customer in its waiting queue, holding it for them until holdUntil. When
+// nobody is waiting, the copy goes back into stock and nil is returned.
+func releaseCopy(tx *sqlx.Tx, bookId int, holdUntil time.Time) (*entity.Reservation, error) {
+	next := new(entity.Reservation)
+	query := "SELECT * FROM Reservations WHERE book_id = $1 AND status = $2 ORDER BY position LIMIT 1 FOR UPDATE"
+	if err := tx.Get(next, query, bookId, entity.ReservationStatusWaiting); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		if _, err := tx.Exec("UPDATE Books SET stock = stock + 1 WHERE id = $1", bookId); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	if _, err := tx.Exec("UPDATE Reservations SET status = $1, hold_expires_at = $2 WHERE id = $3", entity.ReservationStatusHeld, holdUntil, next.ID); err != nil {
+		return nil, err
+	}
+
+	next.Status = entity.ReservationStatusHeld
+	next.HoldExpiresAt = &holdUntil
+	return next, nil
+}
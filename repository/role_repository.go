@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRoleExists        = errors.New("role already exists")
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+type RoleRepository interface {
+	Create(role *entity.Role) error
+	GrantPermissions(roleId int, permissions []string) error
+	RevokePermission(roleId int, permission string) error
+	FindAll() ([]entity.Role, error)
+	FindById(roleId int) (*entity.Role, error)
+	FindByName(name string) (*entity.Role, error)
+	FindPermissionNames(roleName string) ([]string, error)
+	FindAllPermissions() ([]entity.Permission, error)
+}
+
+type RoleRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewRoleRepository(db *sqlx.DB) *RoleRepositoryImpl {
+	return &RoleRepositoryImpl{DB: db}
+}
+
+func (repository *RoleRepositoryImpl) Create(role *entity.Role) error {
+	query := "INSERT INTO Roles (name, description) VALUES ($1, $2) RETURNING id, created_at, updated_at"
+
+	if err := repository.DB.QueryRow(query, role.Name, role.Description).Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrRoleExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GrantPermissions adds the named permissions to the role. Nothing is granted
+// when one of the names is not a known permission.
+func (repository *RoleRepositoryImpl) GrantPermissions(roleId int, permissions []string) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var known int
+	if err := tx.Get(&known, "SELECT COUNT(*) FROM Permissions WHERE name = ANY($1)", pq.Array(permissions)); err != nil {
+		return err
+	}
+
+	if known != len(uniqueStrings(permissions)) {
+		return ErrUnknownPermission
+	}
+
+	query := "INSERT INTO Role_Permissions (role_id, permission_id) SELECT $1, id FROM Permissions WHERE name = ANY($2) ON CONFLICT DO NOTHING"
+	if _, err := tx.Exec(query, roleId, pq.Array(permissions)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokePermission takes the permission from the role. Revoking users:manage
+// from the role of every active admin fails with ErrLastAdmin.
+func (repository *RoleRepositoryImpl) RevokePermission(roleId int, permission string) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if permission == entity.PermissionUsersManage {
+		admins, err := lockActiveAdmins(tx)
+		if err != nil {
+			return err
+		}
+
+		remaining := 0
+		for _, admin := range admins {
+			if admin.RoleID != roleId {
+				remaining++
+			}
+		}
+
+		if len(admins) > 0 && remaining == 0 {
+			return ErrLastAdmin
+		}
+	}
+
+	query := "DELETE FROM Role_Permissions WHERE role_id = $1 AND permission_id = (SELECT id FROM Permissions WHERE name = $2)"
+
+	result, err := tx.Exec(query, roleId, permission)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrUnknownPermission
+	}
+
+	return tx.Commit()
+}
+
+func (repository *RoleRepositoryImpl) FindAll() ([]entity.Role, error) {
+	query := "SELECT * FROM Roles ORDER BY id"
+
+	var roles []entity.Role
+	if err := repository.DB.Select(&roles, query); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (repository *RoleRepositoryImpl) FindById(roleId int) (*entity.Role, error) {
+	query := "SELECT * FROM Roles WHERE id = $1"
+
+	role := new(entity.Role)
+	if err := repository.DB.Get(role, query, roleId); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (repository *RoleRepositoryImpl) FindByName(name string) (*entity.Role, error) {
+	query := "SELECT * FROM Roles WHERE name = $1"
+
+	role := new(entity.Role)
+	if err := repository.DB.Get(role, query, name); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (repository *RoleRepositoryImpl) FindPermissionNames(roleName string) ([]string, error) {
+	query := `SELECT Permissions.name FROM Permissions
+		JOIN Role_Permissions ON Role_Permissions.permission_id = Permissions.id
+		JOIN Roles ON Roles.id = Role_Permissions.role_id
+		WHERE Roles.name = $1
+		ORDER BY Permissions.name`
+
+	permissions := []string{}
+	if err := repository.DB.Select(&permissions, query, roleName); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (repository *RoleRepositoryImpl) FindAllPermissions() ([]entity.Permission, error) {
+	query := "SELECT * FROM Permissions ORDER BY name"
+
+	var permissions []entity.Permission
+	if err := repository.DB.Select(&permissions, query); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func uniqueStrings(values []string) map[string]struct{} {
+	unique := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		unique[value] = struct{}{}
+	}
+
+	return unique
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SessionRepository interface {
+	Create(session *entity.Session) error
+	Touch(sessionId string, seenAt time.Time) error
+	MarkTwoFactorVerified(sessionId string) error
+	Revoke(sessionId string) error
+	RevokeAllByUserId(userId int) error
+	FindById(sessionId string) (*entity.Session, error)
+	FindActiveByUserId(userId int) ([]entity.Session, error)
+	FindByUserId(userId int) ([]entity.Session, error)
+}
+
+type SessionRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepositoryImpl {
+	return &SessionRepositoryImpl{DB: db}
+}
+
+func (repository *SessionRepositoryImpl) Create(session *entity.Session) error {
+	query := "INSERT INTO Sessions (id, user_id, device, ip_address, user_agent, two_factor_verified) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at, last_seen_at"
+
+	if err := repository.DB.QueryRow(query, session.ID, session.UserID, session.Device, session.IPAddress, session.UserAgent, session.TwoFactorVerified).Scan(&session.CreatedAt, &session.LastSeenAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *SessionRepositoryImpl) Touch(sessionId string, seenAt time.Time) error {
+	query := "UPDATE Sessions SET last_seen_at = $1 WHERE id = $2 AND last_seen_at < $1"
+
+	_, err := repository.DB.Exec(query, seenAt, sessionId)
+	return err
+}
+
+func (repository *SessionRepositoryImpl) MarkTwoFactorVerified(sessionId string) error {
+	query := "UPDATE Sessions SET two_factor_verified = true WHERE id = $1"
+
+	_, err := repository.DB.Exec(query, sessionId)
+	return err
+}
+
+// Revoke ends the session together with the refresh tokens issued for it.
+func (repository *SessionRepositoryImpl) Revoke(sessionId string) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE Sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL", sessionId); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE Refresh_Tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL", sessionId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeAllByUserId ends every session of the user together with their
+// refresh tokens.
+func (repository *SessionRepositoryImpl) RevokeAllByUserId(userId int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE Sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userId); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE Refresh_Tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *SessionRepositoryImpl) FindById(sessionId string) (*entity.Session, error) {
+	query := "SELECT * FROM Sessions WHERE id = $1"
+
+	session := new(entity.Session)
+	if err := repository.DB.Get(session, query, sessionId); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (repository *SessionRepositoryImpl) FindActiveByUserId(userId int) ([]entity.Session, error) {
+	query := "SELECT * FROM Sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC"
+
+	sessions := []entity.Session{}
+	if err := repository.DB.Select(&sessions, query, userId); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (repository *SessionRepositoryImpl) FindByUserId(userId int) ([]entity.Session, error) {
+	query := "SELECT * FROM Sessions WHERE user_id = $1 ORDER BY created_at DESC"
+
+	sessions := []entity.Session{}
+	if err := repository.DB.Select(&sessions, query, userId); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
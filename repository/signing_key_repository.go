@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// signingKeyLockKey serialises key rotation across instances.
+const signingKeyLockKey = 7_162_535
+
+type SigningKeyRepository interface {
+	CurrentCreatedAt(algorithm string) (*time.Time, error)
+	RotateIfStale(key *entity.SigningKey, staleBefore time.Time, verifyUntil time.Time) (bool, error)
+	FindActive(now time.Time) ([]entity.SigningKey, error)
+}
+
+type SigningKeyRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewSigningKeyRepository(db *sqlx.DB) *SigningKeyRepositoryImpl {
+	return &SigningKeyRepositoryImpl{DB: db}
+}
+
+// CurrentCreatedAt returns when the current signing key of the algorithm was
+// created, or nil when there is none.
+func (repository *SigningKeyRepositoryImpl) CurrentCreatedAt(algorithm string) (*time.Time, error) {
+	query := "SELECT MAX(created_at) FROM Signing_Keys WHERE algorithm = $1 AND retired_at IS NULL"
+
+	var createdAt *time.Time
+	if err := repository.DB.Get(&createdAt, query, algorithm); err != nil {
+		return nil, err
+	}
+
+	return createdAt, nil
+}
+
+// RotateIfStale stores key as the new signing key of its algorithm when the
+// current one was created before staleBefore, or when there is none. The
+// previous keys are retired and stay valid for verification until
+// verifyUntil. Keys past their expiry are deleted. It reports whether key was
+// stored.
+func (repository *SigningKeyRepositoryImpl) RotateIfStale(key *entity.SigningKey, staleBefore time.Time, verifyUntil time.Time) (bool, error) {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", signingKeyLockKey); err != nil {
+		return false, err
+	}
+
+	var fresh int
+	freshQuery := "SELECT COUNT(*) FROM Signing_Keys WHERE algorithm = $1 AND retired_at IS NULL AND created_at >= $2"
+	if err := tx.Get(&fresh, freshQuery, key.Algorithm, staleBefore); err != nil {
+		return false, err
+	}
+
+	if fresh > 0 {
+		return false, nil
+	}
+
+	retireQuery := "UPDATE Signing_Keys SET retired_at = CURRENT_TIMESTAMP, expires_at = $1 WHERE retired_at IS NULL"
+	if _, err := tx.Exec(retireQuery, verifyUntil); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM Signing_Keys WHERE expires_at < CURRENT_TIMESTAMP"); err != nil {
+		return false, err
+	}
+
+	insertQuery := "INSERT INTO Signing_Keys (kid, algorithm, private_key) VALUES ($1, $2, $3) RETURNING created_at"
+	if err := tx.QueryRow(insertQuery, key.KID, key.Algorithm, key.PrivateKey).Scan(&key.CreatedAt); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (repository *SigningKeyRepositoryImpl) FindActive(now time.Time) ([]entity.SigningKey, error) {
+	query := "SELECT * FROM Signing_Keys WHERE expires_at IS NULL OR expires_at > $1 ORDER BY created_at DESC"
+
+	var keys []entity.SigningKey
+	if err := repository.DB.Select(&keys, query, now); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
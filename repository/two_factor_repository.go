@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"dgw-technical-test/entity"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	ErrChallengeClosed  = errors.New("login challenge is no longer valid")
+)
+
+type TwoFactorRepository interface {
+	SetPendingSecret(userId int, secret string) error
+	Enable(userId int, recoveryCodeHashes []string) error
+	Disable(userId int) error
+	UseStep(userId int, step int64) (bool, error)
+	UseRecoveryCode(userId int, codeHash string) (bool, error)
+	CreateChallenge(challenge *entity.LoginChallenge) error
+	RecordChallengeFailure(challengeId int) (int, error)
+	CompleteChallenge(challengeId int) error
+	FindChallengeByHash(tokenHash string) (*entity.LoginChallenge, error)
+}
+
+type TwoFactorRepositoryImpl struct {
+	DB *sqlx.DB
+}
+
+func NewTwoFactorRepository(db *sqlx.DB) *TwoFactorRepositoryImpl {
+	return &TwoFactorRepositoryImpl{DB: db}
+}
+
+// SetPendingSecret stores a secret that takes effect once Enable confirms
+// it. It fails with ErrTwoFactorEnabled when the user already enabled
+// two-factor authentication.
+func (repository *TwoFactorRepositoryImpl) SetPendingSecret(userId int, secret string) error {
+	query := "UPDATE Users SET totp_secret = $1, totp_last_step = NULL WHERE id = $2 AND totp_enabled_at IS NULL"
+
+	result, err := repository.DB.Exec(query, secret, userId)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+// Enable turns on two-factor authentication with the pending secret and
+// replaces the recovery codes of the user.
+func (repository *TwoFactorRepositoryImpl) Enable(userId int, recoveryCodeHashes []string) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE Users SET totp_enabled_at = CURRENT_TIMESTAMP WHERE id = $1 AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL", userId)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	if err := replaceRecoveryCodes(tx, userId, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *TwoFactorRepositoryImpl) Disable(userId int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE Users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1", userId); err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userId, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records that the code of a time step was used. It reports false
+// when that step or a later one was used before, so a code cannot be
+// replayed.
+func (repository *TwoFactorRepositoryImpl) UseStep(userId int, step int64) (bool, error) {
+	query := "UPDATE Users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)"
+
+	result, err := repository.DB.Exec(query, step, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// UseRecoveryCode marks the recovery code used. It reports false when the
+// user has no such unused code.
+func (repository *TwoFactorRepositoryImpl) UseRecoveryCode(userId int, codeHash string) (bool, error) {
+	query := "UPDATE Recovery_Codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+
+	result, err := repository.DB.Exec(query, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+func (repository *TwoFactorRepositoryImpl) CreateChallenge(challenge *entity.LoginChallenge) error {
+	query := "INSERT INTO Login_Challenges (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at"
+
+	if err := repository.DB.QueryRow(query, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt).Scan(&challenge.ID, &challenge.CreatedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecordChallengeFailure counts a wrong code and returns the attempts so
+// far.
+func (repository *TwoFactorRepositoryImpl) RecordChallengeFailure(challengeId int) (int, error) {
+	query := "UPDATE Login_Challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts"
+
+	var attempts int
+	if err := repository.DB.QueryRow(query, challengeId).Scan(&attempts); err != nil {
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+// CompleteChallenge marks the challenge used. It fails with
+// ErrChallengeClosed when it was already used.
+func (repository *TwoFactorRepositoryImpl) CompleteChallenge(challengeId int) error {
+	query := "UPDATE Login_Challenges SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL"
+
+	result, err := repository.DB.Exec(query, challengeId)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrChallengeClosed
+	}
+
+	return nil
+}
+
+func (repository *TwoFactorRepositoryImpl) FindChallengeByHash(tokenHash string) (*entity.LoginChallenge, error) {
+	query := "SELECT * FROM Login_Challenges WHERE token_hash = $1"
+
+	challenge := new(entity.LoginChallenge)
+	if err := repository.DB.Get(challenge, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func replaceRecoveryCodes(tx *sqlx.Tx, userId int, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM Recovery_Codes WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO Recovery_Codes (user_id, code_hash) VALUES ($1, $2)", userId, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
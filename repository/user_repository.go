@@ -1,15 +1,46 @@
 package repository
 
 import (
+	"database/sql"
 	"dgw-technical-test/entity"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+var (
+	ErrUserHasHistory = errors.New("user has rentals or reservations")
+	ErrLastAdmin      = errors.New("user is the last active admin")
+)
+
+// UserFilter narrows down the users returned by FindAll. Zero values leave
+// the corresponding filter out.
+type UserFilter struct {
+	Search string
+	Role   string
+	Status string
+	Limit  int
+	Offset int
+}
+
 type UserRepository interface {
 	Register(user *entity.User) error
 	FindUserByUsername(username string) (*entity.User, error)
 	FindUserByEmail(email string) (*entity.User, error)
+	FindById(userId int) (*entity.User, error)
+	Update(user *entity.User) error
+	UpdatePassword(userId int, passwordHash string) error
+	UpdateRole(userId int, role string) error
+	CountAdmins() (int, error)
+	FindAll(filter UserFilter) ([]entity.User, int, error)
+	Suspend(userId int) error
+	Reactivate(userId int) error
+	RequirePasswordReset(userId int) error
+	ChangePassword(userId int, passwordHash string) error
+	Delete(userId int) error
 }
 
 type UserRepositoryImpl struct {
@@ -21,9 +52,9 @@ func NewUserRepository(db *sqlx.DB) *UserRepositoryImpl {
 }
 
 func (repository *UserRepositoryImpl) Register(user *entity.User) error {
-	query := "INSERT INTO Users (username, email, password, role) VALUES ($1, $2, $3, $4) RETURNING id"
+	query := "INSERT INTO Users (username, email, password, role, email_verified_at, oidc_issuer, oidc_subject) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id"
 
-	if err := repository.DB.QueryRow(query, user.Username, user.Email, user.Password, user.Role).Scan(&user.ID); err != nil {
+	if err := repository.DB.QueryRow(query, user.Username, user.Email, user.Password, user.Role, user.EmailVerifiedAt, user.OIDCIssuer, user.OIDCSubject).Scan(&user.ID); err != nil {
 		return err
 	}
 
@@ -51,3 +82,300 @@ func (repository *UserRepositoryImpl) FindUserByEmail(email string) (*entity.Use
 
 	return user, nil
 }
+
+func (repository *UserRepositoryImpl) FindById(userId int) (*entity.User, error) {
+	query := "SELECT * FROM Users WHERE id = $1"
+
+	user := new(entity.User)
+	if err := repository.DB.Get(user, query, userId); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Update saves the username, email, password and email verification of the
+// user. The role has its own UpdateRole.
+func (repository *UserRepositoryImpl) Update(user *entity.User) error {
+	query := "UPDATE Users SET username = $1, email = $2, password = $3, email_verified_at = $4 WHERE id = $5 RETURNING updated_at"
+
+	if err := repository.DB.QueryRow(query, user.Username, user.Email, user.Password, user.EmailVerifiedAt, user.ID).Scan(&user.UpdatedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *UserRepositoryImpl) UpdatePassword(userId int, passwordHash string) error {
+	query := "UPDATE Users SET password = $1 WHERE id = $2"
+
+	_, err := repository.DB.Exec(query, passwordHash, userId)
+	return err
+}
+
+// UpdateRole changes the role of the user. Giving the last active admin a
+// role without users:manage fails with ErrLastAdmin.
+func (repository *UserRepositoryImpl) UpdateRole(userId int, role string) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	admins, err := lockActiveAdmins(tx)
+	if err != nil {
+		return err
+	}
+
+	var grantsAdmin bool
+	grantsQuery := `SELECT EXISTS (SELECT 1 FROM Roles
+		JOIN Role_Permissions ON Role_Permissions.role_id = Roles.id
+		JOIN Permissions ON Permissions.id = Role_Permissions.permission_id
+		WHERE Roles.name = $1 AND Permissions.name = $2)`
+	if err := tx.Get(&grantsAdmin, grantsQuery, role, entity.PermissionUsersManage); err != nil {
+		return err
+	}
+
+	if !grantsAdmin && onlyAdmin(admins, userId) {
+		return ErrLastAdmin
+	}
+
+	result, err := tx.Exec("UPDATE Users SET role = $1 WHERE id = $2", role, userId)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// CountAdmins counts the active users whose role grants users:manage.
+func (repository *UserRepositoryImpl) CountAdmins() (int, error) {
+	query := "SELECT COUNT(*) FROM (" + activeAdminsQuery + ") admins"
+
+	var count int
+	if err := repository.DB.Get(&count, query, entity.PermissionUsersManage); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindAll returns one page of the users matching the filter together with
+// the total number of matching users.
+func (repository *UserRepositoryImpl) FindAll(filter UserFilter) ([]entity.User, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Search != "" {
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+		conditions = append(conditions, fmt.Sprintf(`(username ILIKE $%d ESCAPE '\' OR email ILIKE $%d ESCAPE '\')`, len(args), len(args)))
+	}
+
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("role = $%d", len(args)))
+	}
+
+	switch filter.Status {
+	case entity.UserStatusActive:
+		conditions = append(conditions, "suspended_at IS NULL AND email_verified_at IS NOT NULL")
+	case entity.UserStatusUnverified:
+		conditions = append(conditions, "suspended_at IS NULL AND email_verified_at IS NULL")
+	case entity.UserStatusSuspended:
+		conditions = append(conditions, "suspended_at IS NOT NULL")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := repository.DB.Get(&total, "SELECT COUNT(*) FROM Users"+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf("SELECT * FROM Users%s ORDER BY id LIMIT $%d OFFSET $%d", where, len(args)-1, len(args))
+
+	users := []entity.User{}
+	if err := repository.DB.Select(&users, query, args...); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Suspend blocks the user from logging in and signs them out of every
+// session. Suspending the last active admin fails with ErrLastAdmin.
+func (repository *UserRepositoryImpl) Suspend(userId int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := guardLastAdmin(tx, userId); err != nil {
+		return err
+	}
+
+	if err := updateAndSignOut(tx, userId, "UPDATE Users SET suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP) WHERE id = $1"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *UserRepositoryImpl) Reactivate(userId int) error {
+	query := "UPDATE Users SET suspended_at = NULL WHERE id = $1"
+
+	result, err := repository.DB.Exec(query, userId)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RequirePasswordReset blocks the user from logging in until they reset
+// their password, and signs them out of every session.
+func (repository *UserRepositoryImpl) RequirePasswordReset(userId int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateAndSignOut(tx, userId, "UPDATE Users SET password_reset_required = true WHERE id = $1"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ChangePassword replaces the password hash of the user and signs them out of
+// every session.
+func (repository *UserRepositoryImpl) ChangePassword(userId int, passwordHash string) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateAndSignOut(tx, userId, "UPDATE Users SET password = $2 WHERE id = $1", passwordHash); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the user together with their sessions and tokens. Users
+// with rentals or reservations are kept for the records and fail with
+// ErrUserHasHistory; the last active admin fails with ErrLastAdmin.
+func (repository *UserRepositoryImpl) Delete(userId int) error {
+	tx, err := repository.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := guardLastAdmin(tx, userId); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM Users WHERE id = $1", userId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrUserHasHistory
+		}
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// adminLockKey serialises the changes that could leave no active admin.
+const adminLockKey = 7_162_536
+
+// activeAdminsQuery selects the user and role IDs of the admins: the active
+// users whose role grants users:manage.
+const activeAdminsQuery = `SELECT Users.id AS user_id, Roles.id AS role_id FROM Users
+	JOIN Roles ON Roles.name = Users.role
+	JOIN Role_Permissions ON Role_Permissions.role_id = Roles.id
+	JOIN Permissions ON Permissions.id = Role_Permissions.permission_id
+	WHERE Permissions.name = $1 AND Users.suspended_at IS NULL`
+
+type activeAdmin struct {
+	UserID int `db:"user_id"`
+	RoleID int `db:"role_id"`
+}
+
+// lockActiveAdmins takes the admin lock and locks the rows of the active
+// admins. Transactions that could remove an admin, by changing a user or the
+// permissions of a role, all take the lock first, so two of them cannot both
+// count an admin the other one removes.
+func lockActiveAdmins(tx *sqlx.Tx) ([]activeAdmin, error) {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", adminLockKey); err != nil {
+		return nil, err
+	}
+
+	var admins []activeAdmin
+	if err := tx.Select(&admins, activeAdminsQuery+" ORDER BY Users.id FOR UPDATE OF Users", entity.PermissionUsersManage); err != nil {
+		return nil, err
+	}
+
+	return admins, nil
+}
+
+// guardLastAdmin fails with ErrLastAdmin when the user is the only active
+// admin.
+func guardLastAdmin(tx *sqlx.Tx, userId int) error {
+	admins, err := lockActiveAdmins(tx)
+	if err != nil {
+		return err
+	}
+
+	if onlyAdmin(admins, userId) {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+func onlyAdmin(admins []activeAdmin, userId int) bool {
+	return len(admins) == 1 && admins[0].UserID == userId
+}
+
+func updateAndSignOut(tx *sqlx.Tx, userId int, query string, args ...interface{}) error {
+	result, err := tx.Exec(query, append([]interface{}{userId}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec("UPDATE Sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userId); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE Refresh_Tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userId); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"dgw-technical-test/auth"
+	"dgw-technical-test/config"
+	"dgw-technical-test/entity"
 	"dgw-technical-test/handler"
 	"dgw-technical-test/middleware"
+	"dgw-technical-test/repository"
 
 	_ "dgw-technical-test/docs"
 
@@ -10,17 +14,84 @@ import (
 	"github.com/gofiber/swagger"
 )
 
-func NewRoute(app *fiber.App, uh handler.UserHandler, bh handler.BookHandler) {
+func NewRoute(app *fiber.App, keyManager *auth.KeyManager, sessionRepository repository.SessionRepository, userRepository repository.UserRepository, roleRepository repository.RoleRepository, apiKeyRepository repository.ApiKeyRepository, twoFactorPolicy *config.TwoFactorPolicy, uh handler.UserHandler, bh handler.BookHandler, rh handler.RentHandler, rsh handler.ReservationHandler, rlh handler.RoleHandler, ah handler.AdminHandler, sh handler.SessionHandler, ph handler.PasswordResetHandler, evh handler.EmailVerificationHandler, tfh handler.TwoFactorHandler, akh handler.ApiKeyHandler, oh handler.OIDCHandler, pvh handler.PrivacyHandler, jh handler.JWKSHandler) {
 	app.Get("/swagger/*", swagger.HandlerDefault)
+	app.Get("/.well-known/jwks.json", jh.JWKS)
+
+	jwtMiddleware := middleware.CustomJwtMiddleware(keyManager, sessionRepository)
+	apiKeyOrJwt := middleware.CustomApiKeyMiddleware(apiKeyRepository, userRepository, roleRepository, jwtMiddleware)
+	twoFactor := middleware.RequireTwoFactor(twoFactorPolicy)
+	verifiedEmail := middleware.RequireVerifiedEmail(userRepository)
 
 	users := app.Group("/users")
 	users.Post("/register", uh.Register)
 	users.Post("/login", uh.Login)
+	users.Post("/login/2fa", uh.LoginTwoFactor)
+	users.Get("/oidc/login", oh.Login)
+	users.Get("/oidc/callback", oh.Callback)
+	users.Post("/refresh", uh.Refresh)
+	users.Post("/logout", uh.Logout)
+	users.Post("/password-reset", ph.Request)
+	users.Post("/password-reset/confirm", ph.Confirm)
+	users.Post("/verify-email", evh.Confirm)
+	users.Post("/verify-email/resend", evh.Resend)
+	users.Get("/sessions", jwtMiddleware, sh.FindMine)
+	users.Post("/sessions/revoke-all", jwtMiddleware, sh.RevokeAll)
+	users.Delete("/sessions/:id", jwtMiddleware, sh.Revoke)
+	users.Post("/2fa/enroll", jwtMiddleware, tfh.Enroll)
+	users.Post("/2fa/confirm", jwtMiddleware, tfh.Confirm)
+	users.Post("/2fa/disable", jwtMiddleware, tfh.Disable)
+	users.Get("/api-keys", jwtMiddleware, twoFactor, akh.FindMine)
+	users.Post("/api-keys", jwtMiddleware, twoFactor, akh.Create)
+	users.Delete("/api-keys/:id", jwtMiddleware, twoFactor, akh.Revoke)
+	users.Get("/me", jwtMiddleware, twoFactor, uh.FindMe)
+	users.Patch("/me", jwtMiddleware, twoFactor, uh.UpdateMe)
+	users.Put("/me/email", jwtMiddleware, twoFactor, uh.ChangeEmail)
+	users.Put("/me/password", jwtMiddleware, twoFactor, uh.ChangePassword)
+	users.Get("/me/export", jwtMiddleware, twoFactor, pvh.ExportMine)
+	users.Post("/me/erasure", jwtMiddleware, twoFactor, pvh.RequestErasure)
+
+	books := app.Group("/books", apiKeyOrJwt, twoFactor)
+	books.Post("/", middleware.RequirePermission(entity.PermissionBooksWrite), bh.Create)
+	books.Put("/:id", middleware.RequirePermission(entity.PermissionBooksWrite), bh.Update)
+	books.Delete("/:id", middleware.RequirePermission(entity.PermissionBooksWrite), bh.Delete)
+	books.Get("/", middleware.RequirePermission(entity.PermissionBooksRead), bh.FindAll)
+	books.Get("/search", middleware.RequirePermission(entity.PermissionBooksRead), bh.Search)
+	books.Get("/:id", middleware.RequirePermission(entity.PermissionBooksRead), bh.FindById)
+
+	rents := app.Group("/rents", apiKeyOrJwt, twoFactor)
+	rents.Post("/", middleware.RequirePermission(entity.PermissionRentsCreate), verifiedEmail, rh.Create)
+	rents.Get("/me", middleware.RequirePermission(entity.PermissionRentsSelf), rh.FindMine)
+	rents.Get("/", middleware.RequirePermission(entity.PermissionRentsReadAll), rh.FindAll)
+	rents.Post("/:id/return", middleware.RequirePermission(entity.PermissionRentsSelf, entity.PermissionRentsManage), rh.Return)
+	rents.Post("/:id/renew", middleware.RequirePermission(entity.PermissionRentsSelf), rh.Renew)
+
+	reservations := app.Group("/reservations", apiKeyOrJwt, twoFactor)
+	reservations.Post("/", middleware.RequirePermission(entity.PermissionReservationsCreate), verifiedEmail, rsh.Create)
+	reservations.Get("/me", middleware.RequirePermission(entity.PermissionReservationsSelf), rsh.FindMine)
+	reservations.Get("/books/:id", middleware.RequirePermission(entity.PermissionReservationsManage), rsh.FindQueue)
+	reservations.Put("/:id/position", middleware.RequirePermission(entity.PermissionReservationsManage), rsh.Move)
+	reservations.Delete("/:id", middleware.RequirePermission(entity.PermissionReservationsSelf, entity.PermissionReservationsManage), rsh.Cancel)
+
+	roles := app.Group("/roles", apiKeyOrJwt, twoFactor, middleware.RequirePermission(entity.PermissionRolesManage))
+	roles.Post("/", rlh.Create)
+	roles.Get("/", rlh.FindAll)
+	roles.Post("/:id/permissions", rlh.GrantPermissions)
+	roles.Delete("/:id/permissions/:permission", rlh.RevokePermission)
+
+	app.Get("/permissions", apiKeyOrJwt, twoFactor, middleware.RequirePermission(entity.PermissionRolesManage), rlh.FindAllPermissions)
 
-	books := app.Group("/books", middleware.CustomJwtMiddleware())
-	books.Post("/", bh.Create)
-	books.Put("/:id", bh.Update)
-	books.Delete("/:id", bh.Delete)
-	books.Get("/", bh.FindAll)
-	books.Get("/:id", bh.FindById)
+	admin := app.Group("/admin", apiKeyOrJwt, twoFactor, middleware.RequirePermission(entity.PermissionUsersManage))
+	admin.Get("/users", ah.FindAllUsers)
+	admin.Post("/users", ah.CreateUser)
+	admin.Get("/users/:id", ah.FindUserById)
+	admin.Delete("/users/:id", ah.DeleteUser)
+	admin.Put("/users/:id/role", ah.UpdateUserRole)
+	admin.Post("/users/:id/unlock", ah.UnlockUser)
+	admin.Post("/users/:id/suspend", ah.SuspendUser)
+	admin.Post("/users/:id/reactivate", ah.ReactivateUser)
+	admin.Post("/users/:id/password-reset", ah.ForcePasswordReset)
+	admin.Get("/erasure-requests", pvh.FindErasureRequests)
+	admin.Post("/erasure-requests/:id/complete", pvh.CompleteErasure)
+	admin.Post("/erasure-requests/:id/reject", pvh.RejectErasure)
 }